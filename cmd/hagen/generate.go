@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	// Snapshot is everything the generator needs from Home Assistant. It can
+	// be saved with -save and used offline with -snapshot.
+	Snapshot struct {
+		States   types.Entities `json:"states"`
+		Services types.Services `json:"services"`
+	}
+
+	domainEntities struct {
+		Name     string
+		Domain   string
+		Entities []namedEntity
+	}
+
+	namedEntity struct {
+		Name         string
+		ID           string
+		FriendlyName string
+	}
+
+	serviceCall struct {
+		Name        string
+		Domain      string
+		Service     string
+		Description string
+		HasTarget   bool
+		Required    []serviceField
+		Optional    []serviceField
+	}
+
+	serviceField struct {
+		Name        string // Setter name
+		Param       string // Constructor parameter name
+		Key         string
+		Type        string
+		Description string
+	}
+)
+
+// Names of the methods promoted from services.Call, which setters can't shadow.
+var reservedSetters = map[string]bool{"Call": true, "Set": true, "Params": true}
+
+// Names used by the generated constructors, which parameters can't shadow.
+var reservedParams = map[string]bool{"target": true, "call": true, "services": true, "types": true, "entity": true}
+
+// Generate the source files of a package named pkg, keyed by file name.
+func generate(snap Snapshot, pkg string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	topLevel := newNamer() // Every identifier declared at package level
+
+	if len(snap.States) > 0 {
+		src, err := render(entitiesTemplate, map[string]any{
+			"Package": pkg,
+			"Domains": groupEntities(snap.States, topLevel),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate entities: %w", err)
+		}
+
+		files["entities.go"] = src
+	}
+
+	if len(snap.Services) > 0 {
+		calls := serviceCalls(snap.Services, topLevel)
+
+		usesEntity := false
+
+		for _, call := range calls {
+			for _, field := range append(call.Required, call.Optional...) {
+				if strings.HasPrefix(field.Type, "entity.") {
+					usesEntity = true
+				}
+			}
+		}
+
+		src, err := render(servicesTemplate, map[string]any{
+			"Package":    pkg,
+			"Services":   calls,
+			"UsesEntity": usesEntity,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate services: %w", err)
+		}
+
+		files["services.go"] = src
+	}
+
+	return files, nil
+}
+
+func render(tmpl *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %w", err)
+	}
+
+	return src, nil
+}
+
+// Group entities by domain, sorted by name.
+func groupEntities(states types.Entities, topLevel *namer) []domainEntities {
+	byDomain := make(map[string][]types.Entity)
+	for _, e := range states {
+		domain := string(e.EntityID.Domain())
+		byDomain[domain] = append(byDomain[domain], e)
+	}
+
+	groups := make([]domainEntities, 0, len(byDomain))
+
+	for _, domain := range sortedKeys(byDomain) {
+		entities := byDomain[domain]
+		sort.Slice(entities, func(i, j int) bool {
+			return entities[i].EntityID.Name() < entities[j].EntityID.Name()
+		})
+
+		group := domainEntities{Name: topLevel.name(goName(domain)), Domain: domain}
+		names := newNamer()
+
+		for _, e := range entities {
+			var attributes struct {
+				FriendlyName string `json:"friendly_name"`
+			}
+
+			_ = e.UnmarshalAttributes(&attributes)
+
+			group.Entities = append(group.Entities, namedEntity{
+				Name:         names.name(goName(e.EntityID.Name())),
+				ID:           e.EntityID.String(),
+				FriendlyName: oneLine(attributes.FriendlyName),
+			})
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// Build a call for every service, sorted by domain and service.
+func serviceCalls(services types.Services, topLevel *namer) []serviceCall {
+	var calls []serviceCall
+
+	for _, domain := range sortedKeys(services) {
+		for _, service := range sortedKeys(services[domain]) {
+			svc := services[domain][service]
+			call := serviceCall{
+				Name:        topLevel.pair(goName(domain)+goName(service), "Call"),
+				Domain:      domain,
+				Service:     service,
+				Description: oneLine(svc.Description),
+				HasTarget:   svc.Target != nil,
+			}
+
+			setters := newNamer()
+			for name := range reservedSetters {
+				setters.used[name] = true
+			}
+
+			params := newNamer()
+			for name := range reservedParams {
+				params.used[name] = true
+			}
+
+			for _, key := range sortedKeys(svc.Fields) {
+				field := svc.Fields[key]
+				f := serviceField{
+					Key:         key,
+					Type:        fieldType(field.Selector),
+					Description: oneLine(field.Description),
+				}
+
+				if field.Required != nil && *field.Required {
+					f.Param = params.name(lowerFirst(goName(key)))
+					call.Required = append(call.Required, f)
+				} else {
+					f.Name = setters.name(goName(key))
+					call.Optional = append(call.Optional, f)
+				}
+			}
+
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Pick the Go type for a field from its selector. Fields with selectors that
+// don't map to a simple type are left as any.
+func fieldType(selector map[string]any) string {
+	for name, options := range selector {
+		var multiple bool
+		if opts, ok := options.(map[string]any); ok {
+			multiple, _ = opts["multiple"].(bool)
+		}
+
+		switch name {
+		case "boolean":
+			return "bool"
+		case "number":
+			return "float64"
+		case "text", "select", "time", "date", "datetime", "icon", "theme", "template":
+			if multiple {
+				return "[]string"
+			}
+
+			return "string"
+		case "entity":
+			if multiple {
+				return "entity.IDList"
+			}
+
+			return "entity.ID"
+		case "color_rgb":
+			return "[]int"
+		}
+	}
+
+	return "any"
+}
+
+// Words written in upper case in Go identifiers.
+var initialisms = map[string]bool{
+	"hs": true, "hvac": true, "id": true, "ip": true, "rgb": true, "rgbw": true,
+	"rgbww": true, "tts": true, "url": true, "uv": true, "xy": true,
+}
+
+// Convert a snake_case Home Assistant name into an exported Go identifier.
+func goName(s string) string {
+	var b strings.Builder
+
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+
+		runes := []rune(part)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "N" + name
+	}
+
+	return name
+}
+
+// Convert an exported Go identifier into an unexported one, lowering a
+// leading initialism as a whole.
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	name := string(runes)
+	if token.IsKeyword(name) {
+		name += "Value"
+	}
+
+	return name
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Hands out unique names, numbering any that were already taken.
+type namer struct {
+	used map[string]bool
+}
+
+func newNamer() *namer {
+	return &namer{used: make(map[string]bool)}
+}
+
+// Pick a unique name that is also unique with suffix appended, for a function
+// and the type it returns.
+func (n *namer) pair(name, suffix string) string {
+	unique := name
+	for i := 2; n.used[unique] || n.used[unique+suffix]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+
+	n.used[unique] = true
+	n.used[unique+suffix] = true
+
+	return unique
+}
+
+func (n *namer) name(name string) string {
+	unique := name
+	for i := 2; n.used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+
+	n.used[unique] = true
+
+	return unique
+}
+
+var entitiesTemplate = template.Must(template.New("entities").Parse(`// Code generated by hagen. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+{{range .Domains}}
+// {{.Name}} holds the entities in the {{.Domain}} domain.
+var {{.Name}} = struct {
+{{- range .Entities}}
+	{{.Name}} entity.ID{{with .FriendlyName}} // {{.}}{{end}}
+{{- end}}
+}{
+{{- range .Entities}}
+	{{.Name}}: entity.MustParse({{printf "%q" .ID}}),
+{{- end}}
+}
+{{end}}`))
+
+var servicesTemplate = template.Must(template.New("services").Parse(`// Code generated by hagen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+{{- if .UsesEntity}}
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+{{- end}}
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+{{range .Services}}{{$call := .}}
+// {{.Name}}Call is a call to {{.Domain}}.{{.Service}}.
+type {{.Name}}Call struct {
+	*services.Call
+}
+
+// {{.Name}} calls {{.Domain}}.{{.Service}}.{{with .Description}} {{.}}{{end}}
+func {{.Name}}(
+{{- if .HasTarget}}target types.ServiceTarget{{if .Required}}, {{end}}{{end}}
+{{- range $i, $f := .Required}}{{if $i}}, {{end}}{{$f.Param}} {{$f.Type}}{{end}}) *{{.Name}}Call {
+	call := services.New({{printf "%q" .Domain}}, {{printf "%q" .Service}},
+		{{- if .HasTarget}} target{{else}} types.ServiceTarget{}{{end}})
+{{- range .Required}}
+	call.Set({{printf "%q" .Key}}, {{.Param}})
+{{- end}}
+
+	return &{{.Name}}Call{call}
+}
+{{range .Optional}}
+// {{.Name}} sets {{.Key}}.{{with .Description}} {{.}}{{end}}
+func (c *{{$call.Name}}Call) {{.Name}}(value {{.Type}}) *{{$call.Name}}Call {
+	c.Call.Set({{printf "%q" .Key}}, value)
+	return c
+}
+{{end}}{{end}}`))
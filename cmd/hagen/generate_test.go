@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := os.ReadFile("testdata/snapshot.json")
+	require.NoError(t, err)
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+
+	files, err := generate(snap, "ha")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	typeCheck(t, files)
+
+	entities := string(files["entities.go"])
+	assert.Contains(t, entities, "package ha")
+	assert.Contains(t, entities, `KitchenCeiling: entity.MustParse("light.kitchen_ceiling")`)
+	assert.Contains(t, entities, `N1stFloor:      entity.MustParse("light.1st_floor")`)
+	assert.Contains(t, entities, "OutdoorTemperature entity.ID // Outdoor temperature")
+
+	services := string(files["services.go"])
+	assert.Contains(t, services, "func LightTurnOn(target types.ServiceTarget) *LightTurnOnCall")
+	assert.Contains(t, services, "func (c *LightTurnOnCall) RGBColor(value []int) *LightTurnOnCall")
+	assert.Contains(t, services, "func (c *LightTurnOnCall) Brightness(value float64) *LightTurnOnCall")
+	assert.Contains(t, services, "func LockSetCode(target types.ServiceTarget, code string, typeValue string) *LockSetCodeCall")
+	assert.Contains(t, services, "func (c *LockSetCodeCall) Params2(value any) *LockSetCodeCall")
+	assert.Contains(t, services, "func HomeassistantUpdateEntity(entityID entity.IDList) *HomeassistantUpdateEntityCall")
+	assert.Contains(t, services, "func HomeassistantRestart() *HomeassistantRestartCall")
+}
+
+// Type-check the generated package against the packages it imports, which
+// catches bad imports, name collisions and mismatched types.
+func typeCheck(t *testing.T, files map[string][]byte) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	parsed := make([]*ast.File, 0, len(files))
+
+	for name, src := range files {
+		f, err := parser.ParseFile(fset, name, src, 0)
+		require.NoError(t, err)
+
+		parsed = append(parsed, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+
+	_, err := conf.Check("ha", fset, parsed, nil)
+	require.NoError(t, err)
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "KitchenCeiling", goName("kitchen_ceiling"))
+	assert.Equal(t, "N2ndFloor", goName("2nd_floor"))
+	assert.Equal(t, "HVACMode", goName("hvac_mode"))
+	assert.Equal(t, "hvacMode", lowerFirst(goName("hvac_mode")))
+	assert.Equal(t, "rangeValue", lowerFirst(goName("range")))
+}
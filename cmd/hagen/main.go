@@ -0,0 +1,128 @@
+// hagen generates a Go package of entity IDs and typed service calls from a
+// Home Assistant instance, so that typos in entity IDs and service fields
+// become compile errors.
+//
+// Generate from a live instance, optionally saving a snapshot:
+//
+//	HA_TOKEN=... hagen -host homeassistant.local:8123 -out ./ha -save snapshot.json
+//
+// Or offline from a saved snapshot:
+//
+//	hagen -snapshot snapshot.json -out ./ha
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ryanjohnsontv/go-homeassistant/websocket"
+)
+
+func main() {
+	var (
+		host     = flag.String("host", "", "Home Assistant host and port to fetch states and services from")
+		token    = flag.String("token", os.Getenv("HA_TOKEN"), "Long-lived access token, defaults to $HA_TOKEN")
+		secure   = flag.Bool("secure", false, "Connect with wss")
+		snapshot = flag.String("snapshot", "", "Generate from a saved snapshot instead of a live instance")
+		save     = flag.String("save", "", "Save the fetched states and services to a snapshot file")
+		out      = flag.String("out", "ha", "Directory to write the generated package to")
+		pkg      = flag.String("pkg", "", "Package name, defaults to the name of the output directory")
+	)
+
+	flag.Parse()
+
+	if *pkg == "" {
+		*pkg = filepath.Base(*out)
+	}
+
+	snap, err := loadSnapshot(*host, *token, *secure, *snapshot)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *save != "" {
+		if err := saveSnapshot(*save, snap); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	files, err := generate(snap, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(*out, name), src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// Read a snapshot from a file, or fetch one from a live instance.
+func loadSnapshot(host, token string, secure bool, path string) (Snapshot, error) {
+	var snap Snapshot
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return snap, err
+		}
+
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return snap, fmt.Errorf("failed to parse snapshot: %w", err)
+		}
+
+		return snap, nil
+	}
+
+	if host == "" {
+		return snap, fmt.Errorf("either -host or -snapshot is required")
+	}
+
+	var options []websocket.ClientOption
+	if secure {
+		options = append(options, websocket.WithSecureConnection())
+	}
+
+	client, err := websocket.NewClient(host, token, options...)
+	if err != nil {
+		return snap, err
+	}
+	defer client.Close()
+
+	states, err := client.GetStates()
+	if err != nil {
+		return snap, err
+	}
+
+	for _, e := range states {
+		snap.States = append(snap.States, e)
+	}
+
+	sort.Slice(snap.States, func(i, j int) bool {
+		return snap.States[i].EntityID.String() < snap.States[j].EntityID.String()
+	})
+
+	snap.Services, err = client.GetServices()
+
+	return snap, err
+}
+
+func saveSnapshot(path string, snap Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
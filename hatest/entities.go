@@ -0,0 +1,158 @@
+package hatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Send the current states as the first event of the subscription, then push
+// changes as they happen in the same compressed format as Home Assistant.
+func handleSubscribeEntities(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EntityIDs []entity.ID `json:"entity_ids"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	sub := subscription{entities: true}
+	if len(request.EntityIDs) > 0 {
+		sub.entityIDs = make(map[entity.ID]bool, len(request.EntityIDs))
+		for _, id := range request.EntityIDs {
+			sub.entityIDs[id] = true
+		}
+	}
+
+	c.mu.Lock()
+	c.subscriptions[msg.ID] = sub
+	c.mu.Unlock()
+
+	c.SendResult(msg.ID, nil)
+
+	added := make(map[string]any)
+
+	for _, e := range c.server.sortedStates() {
+		if sub.includes(e.EntityID) {
+			added[e.EntityID.String()] = compressState(e)
+		}
+	}
+
+	c.SendEvent(msg.ID, map[string]any{"a": added})
+
+	return nil, ErrNoResult
+}
+
+func (sub subscription) includes(id entity.ID) bool {
+	return sub.entityIDs == nil || sub.entityIDs[id]
+}
+
+// Push a state change to every subscribe_entities subscription that includes it.
+func (s *Server) pushEntities(change types.StateChange) {
+	id := change.EntityID.String()
+
+	var event map[string]any
+
+	switch {
+	case change.NewState == nil:
+		event = map[string]any{"r": []string{id}}
+	case change.OldState == nil:
+		event = map[string]any{"a": map[string]any{id: compressState(*change.NewState)}}
+	default:
+		event = map[string]any{"c": map[string]any{id: diffStates(*change.OldState, *change.NewState)}}
+	}
+
+	for _, conn := range s.connections() {
+		for _, subID := range conn.matching(func(sub subscription) bool {
+			return sub.entities && sub.includes(change.EntityID)
+		}) {
+			conn.SendEvent(subID, event)
+		}
+	}
+}
+
+func compressState(e types.Entity) map[string]any {
+	compressed := map[string]any{
+		"s":  e.State,
+		"a":  e.Attributes,
+		"c":  compressContext(e.Context),
+		"lc": unixFloat(e.LastChanged),
+	}
+
+	if !e.LastUpdated.Equal(e.LastChanged) {
+		compressed["lu"] = unixFloat(e.LastUpdated)
+	}
+
+	return compressed
+}
+
+// Only what changed between two states, with added or changed attributes under
+// "+" and removed attributes under "-".
+func diffStates(oldState, newState types.Entity) map[string]any {
+	additions := make(map[string]any)
+
+	if oldState.State != newState.State {
+		additions["s"] = newState.State
+	}
+
+	if oldState.Context.ID != newState.Context.ID {
+		additions["c"] = compressContext(newState.Context)
+	}
+
+	if !oldState.LastChanged.Equal(newState.LastChanged) {
+		additions["lc"] = unixFloat(newState.LastChanged)
+	} else if !oldState.LastUpdated.Equal(newState.LastUpdated) {
+		additions["lu"] = unixFloat(newState.LastUpdated)
+	}
+
+	var oldAttributes, newAttributes map[string]json.RawMessage
+
+	_ = json.Unmarshal(oldState.Attributes, &oldAttributes)
+	_ = json.Unmarshal(newState.Attributes, &newAttributes)
+
+	changed := make(map[string]json.RawMessage)
+
+	for key, value := range newAttributes {
+		if !bytes.Equal(oldAttributes[key], value) {
+			changed[key] = value
+		}
+	}
+
+	if len(changed) > 0 {
+		additions["a"] = changed
+	}
+
+	diff := map[string]any{"+": additions}
+
+	var removed []string
+
+	for key := range oldAttributes {
+		if _, exists := newAttributes[key]; !exists {
+			removed = append(removed, key)
+		}
+	}
+
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		diff["-"] = map[string]any{"a": removed}
+	}
+
+	return diff
+}
+
+func compressContext(context types.Context) any {
+	if context.UserID == nil && context.ParentID == nil {
+		return context.ID
+	}
+
+	return context
+}
+
+func unixFloat(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
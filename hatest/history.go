@@ -0,0 +1,163 @@
+package hatest
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// The fields of history and logbook commands. The start time state is not
+// supported and device IDs are ignored.
+type streamRequest struct {
+	Type            string      `json:"type"`
+	StartTime       time.Time   `json:"start_time"`
+	EndTime         *time.Time  `json:"end_time"` // Live updates are sent until unsubscribed if unset
+	EntityIDs       []entity.ID `json:"entity_ids"`
+	MinimalResponse bool        `json:"minimal_response"`
+	NoAttributes    bool        `json:"no_attributes"`
+}
+
+func handleHistoryDuringPeriod(c *Conn, msg Message) (any, error) {
+	var request streamRequest
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	return c.server.compressedHistory(request), nil
+}
+
+// Send the recorded history or logbook as the first event, then push state
+// changes until unsubscribed unless an end time was given.
+func handleStream(c *Conn, msg Message) (any, error) {
+	var request streamRequest
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	c.mu.Lock()
+	c.subscriptions[msg.ID] = subscription{stream: &request}
+	c.mu.Unlock()
+
+	c.SendResult(msg.ID, nil)
+
+	end := time.Now()
+	if request.EndTime != nil {
+		end = *request.EndTime
+	}
+
+	event := map[string]any{"start_time": unixFloat(request.StartTime), "end_time": unixFloat(end)}
+
+	if request.Type == "logbook/event_stream" {
+		events := []map[string]any{}
+
+		for _, entityID := range c.server.streamEntities(request) {
+			for _, e := range c.server.historyBetween(entityID, request.StartTime, end) {
+				events = append(events, logbookEvent(e))
+			}
+		}
+
+		event["events"] = events
+		event["partial"] = false
+	} else {
+		event["states"] = c.server.compressedHistory(request)
+	}
+
+	c.SendEvent(msg.ID, event)
+
+	return nil, ErrNoResult
+}
+
+// Push a state change to the live history and logbook streams that include it.
+// The logbook only gets changes of the state itself.
+func (s *Server) pushStreams(change types.StateChange) {
+	e := *change.NewState
+	timestamp := unixFloat(e.LastUpdated)
+
+	for _, conn := range s.connections() {
+		conn.mu.Lock()
+		streams := make(map[int64]*streamRequest)
+		for id, sub := range conn.subscriptions {
+			if sub.stream != nil && sub.stream.EndTime == nil &&
+				(len(sub.stream.EntityIDs) == 0 || slices.Contains(sub.stream.EntityIDs, e.EntityID)) {
+				streams[id] = sub.stream
+			}
+		}
+		conn.mu.Unlock()
+
+		for id, request := range streams {
+			event := map[string]any{"start_time": timestamp, "end_time": timestamp}
+
+			if request.Type == "logbook/event_stream" {
+				if change.OldState != nil && change.OldState.State == e.State {
+					continue
+				}
+
+				event["events"] = []map[string]any{logbookEvent(e)}
+			} else {
+				event["states"] = map[string]any{e.EntityID.String(): []map[string]any{historyRow(e, true, request.NoAttributes)}}
+			}
+
+			conn.SendEvent(id, event)
+		}
+	}
+}
+
+// The recorded states of the requested entities in the compressed format.
+func (s *Server) compressedHistory(request streamRequest) map[string][]map[string]any {
+	end := time.Now()
+	if request.EndTime != nil {
+		end = *request.EndTime
+	}
+
+	history := make(map[string][]map[string]any)
+
+	for _, entityID := range s.streamEntities(request) {
+		var rows []map[string]any
+
+		for _, e := range s.historyBetween(entityID, request.StartTime, end) {
+			rows = append(rows, historyRow(e, !request.MinimalResponse || len(rows) == 0, request.NoAttributes))
+		}
+
+		if len(rows) > 0 {
+			history[entityID.String()] = rows
+		}
+	}
+
+	return history
+}
+
+// The requested entities, or every entity with history.
+func (s *Server) streamEntities(request streamRequest) []entity.ID {
+	if len(request.EntityIDs) > 0 {
+		return request.EntityIDs
+	}
+
+	return s.historyEntities("")
+}
+
+// A compressed history state. Minimal rows leave out the attributes.
+func historyRow(e types.Entity, full, noAttributes bool) map[string]any {
+	row := map[string]any{"s": e.State, "lu": unixFloat(e.LastUpdated)}
+
+	if !e.LastChanged.Equal(e.LastUpdated) {
+		row["lc"] = unixFloat(e.LastChanged)
+	}
+
+	if full && !noAttributes {
+		row["a"] = e.Attributes
+	}
+
+	return row
+}
+
+func logbookEvent(e types.Entity) map[string]any {
+	return map[string]any{
+		"when":       unixFloat(e.LastChanged),
+		"entity_id":  e.EntityID,
+		"state":      e.State,
+		"name":       e.EntityID.Name(),
+		"context_id": e.Context.ID,
+	}
+}
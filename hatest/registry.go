@@ -0,0 +1,474 @@
+package hatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// WithEntityRegistry loads the initial entity registry.
+func WithEntityRegistry(entries ...types.EntityRegistryEntryExtended) Option {
+	return func(s *Server) {
+		for _, e := range entries {
+			s.entityRegistry[e.EntityID] = e
+		}
+	}
+}
+
+// EntityRegistryEntry returns the registry entry of an entity.
+func (s *Server) EntityRegistryEntry(entityID string) (types.EntityRegistryEntryExtended, bool) {
+	id, err := entity.Parse(entityID)
+	if err != nil {
+		return types.EntityRegistryEntryExtended{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entityRegistry[id]
+
+	return e, exists
+}
+
+// SetEntityRegistryEntry adds or replaces a registry entry and pushes an
+// entity_registry_updated event to subscribers.
+func (s *Server) SetEntityRegistryEntry(e types.EntityRegistryEntryExtended) {
+	s.mu.Lock()
+	_, existed := s.entityRegistry[e.EntityID]
+	s.entityRegistry[e.EntityID] = e
+	s.mu.Unlock()
+
+	action := "create"
+	if existed {
+		action = "update"
+	}
+
+	s.FireEvent("entity_registry_updated", map[string]any{"action": action, "entity_id": e.EntityID})
+}
+
+func handleEntityRegistryList(c *Conn, _ Message) (any, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	entries := make([]types.EntityRegistryEntry, 0, len(c.server.entityRegistry))
+	for _, e := range c.server.entityRegistry {
+		entries = append(entries, e.EntityRegistryEntry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].EntityID.String() < entries[j].EntityID.String()
+	})
+
+	return entries, nil
+}
+
+func handleEntityRegistryGet(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EntityID entity.ID `json:"entity_id"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	e, exists := c.server.entityRegistry[request.EntityID]
+	if !exists {
+		return nil, Error{Code: "not_found", Message: "Entity not found"}
+	}
+
+	return e, nil
+}
+
+func handleEntityRegistryUpdate(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EntityID entity.ID `json:"entity_id"`
+	}
+
+	var fields map[string]json.RawMessage
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	if err := msg.Decode(&fields); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	s := c.server
+
+	s.mu.Lock()
+	e, exists := s.entityRegistry[request.EntityID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, Error{Code: "not_found", Message: "Entity not found"}
+	}
+
+	// Decode the changed fields over the entry, keeping the old values of
+	// every changed field for the event.
+	changes := make(map[string]json.RawMessage)
+	old, _ := json.Marshal(e)
+
+	var oldFields map[string]json.RawMessage
+
+	_ = json.Unmarshal(old, &oldFields)
+
+	for key, value := range fields {
+		switch key {
+		case "id", "type", "entity_id":
+			continue
+		case "new_entity_id":
+			if err := json.Unmarshal(value, &e.EntityID); err != nil {
+				s.mu.Unlock()
+				return nil, Error{Code: "invalid_format", Message: err.Error()}
+			}
+
+			continue
+		}
+
+		changes[key] = oldFields[key]
+		patch, _ := json.Marshal(map[string]json.RawMessage{key: value})
+
+		if err := json.Unmarshal(patch, &e); err != nil {
+			s.mu.Unlock()
+			return nil, Error{Code: "invalid_format", Message: err.Error()}
+		}
+	}
+
+	e.ModifiedAt = types.UnixTime(float64(time.Now().UnixNano()) / 1e9)
+
+	delete(s.entityRegistry, request.EntityID)
+	s.entityRegistry[e.EntityID] = e
+	s.mu.Unlock()
+
+	event := map[string]any{"action": "update", "entity_id": e.EntityID, "changes": changes}
+	if e.EntityID != request.EntityID {
+		event["old_entity_id"] = request.EntityID
+	}
+
+	s.FireEvent("entity_registry_updated", event)
+
+	return map[string]any{"entity_entry": e}, nil
+}
+
+func handleEntityRegistryRemove(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EntityID entity.ID `json:"entity_id"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	s := c.server
+
+	s.mu.Lock()
+	_, exists := s.entityRegistry[request.EntityID]
+	delete(s.entityRegistry, request.EntityID)
+	s.mu.Unlock()
+
+	if !exists {
+		return nil, Error{Code: "not_found", Message: "Entity not found"}
+	}
+
+	s.FireEvent("entity_registry_updated", map[string]any{"action": "remove", "entity_id": request.EntityID})
+
+	return nil, nil
+}
+
+// A registry stored as JSON objects keyed by ID, so that updates can be
+// applied field by field. Used for the device, area, floor and label registries.
+type registry struct {
+	idKey string
+	event string
+	items map[string]map[string]any
+}
+
+func newRegistries() map[string]*registry {
+	registries := make(map[string]*registry)
+
+	for _, name := range []string{"device", "area", "floor", "label"} {
+		idKey := name + "_id"
+		if name == "device" {
+			idKey = "id"
+		}
+
+		registries[name] = &registry{
+			idKey: idKey,
+			event: name + "_registry_updated",
+			items: make(map[string]map[string]any),
+		}
+	}
+
+	return registries
+}
+
+// WithDevices loads the initial device registry.
+func WithDevices(devices ...types.Device) Option {
+	return withRegistryItems("device", devices)
+}
+
+// WithAreas loads the initial area registry.
+func WithAreas(areas ...types.Area) Option {
+	return withRegistryItems("area", areas)
+}
+
+// WithFloors loads the initial floor registry.
+func WithFloors(floors ...types.Floor) Option {
+	return withRegistryItems("floor", floors)
+}
+
+// WithLabels loads the initial label registry.
+func WithLabels(labels ...types.Label) Option {
+	return withRegistryItems("label", labels)
+}
+
+func withRegistryItems[T any](name string, items []T) Option {
+	return func(s *Server) {
+		r := s.registries[name]
+
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				panic(err)
+			}
+
+			var fields map[string]any
+			if err := json.Unmarshal(data, &fields); err != nil {
+				panic(err)
+			}
+
+			id, _ := fields[r.idKey].(string)
+			r.items[id] = fields
+		}
+	}
+}
+
+func handleRegistryList(name string) CommandHandler {
+	return func(c *Conn, _ Message) (any, error) {
+		c.server.mu.Lock()
+		defer c.server.mu.Unlock()
+
+		r := c.server.registries[name]
+		items := make([]map[string]any, 0, len(r.items))
+
+		for _, id := range sortedIDs(r.items) {
+			items = append(items, maps.Clone(r.items[id]))
+		}
+
+		return items, nil
+	}
+}
+
+func handleRegistryCreate(name string) CommandHandler {
+	return func(c *Conn, msg Message) (any, error) {
+		fields, err := registryFields(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		itemName, _ := fields["name"].(string)
+		if itemName == "" {
+			return nil, Error{Code: "invalid_format", Message: "required key not provided @ data['name']"}
+		}
+
+		s := c.server
+
+		s.mu.Lock()
+		r := s.registries[name]
+
+		id := slug(itemName)
+		for i := 2; r.items[id] != nil; i++ {
+			id = fmt.Sprintf("%s_%d", slug(itemName), i)
+		}
+
+		now := float64(time.Now().UnixNano()) / 1e9
+		fields[r.idKey] = id
+		fields["created_at"] = now
+		fields["modified_at"] = now
+		r.items[id] = fields
+		created := maps.Clone(fields)
+		s.mu.Unlock()
+
+		s.FireEvent(r.event, map[string]any{"action": "create", r.idKey: id})
+
+		return created, nil
+	}
+}
+
+func handleRegistryUpdate(name string) CommandHandler {
+	return func(c *Conn, msg Message) (any, error) {
+		fields, err := registryFields(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		s := c.server
+
+		s.mu.Lock()
+		r := s.registries[name]
+		id, _ := fields[registryKey(r)].(string)
+
+		item, exists := r.items[id]
+		if !exists {
+			s.mu.Unlock()
+			return nil, Error{Code: "not_found", Message: fmt.Sprintf("%s not found", name)}
+		}
+
+		delete(fields, registryKey(r))
+
+		for k, v := range fields {
+			item[k] = v
+		}
+
+		item["modified_at"] = float64(time.Now().UnixNano()) / 1e9
+		updated := maps.Clone(item)
+		s.mu.Unlock()
+
+		s.FireEvent(r.event, map[string]any{"action": "update", r.idKey: id})
+
+		return updated, nil
+	}
+}
+
+func handleRegistryDelete(name string) CommandHandler {
+	return func(c *Conn, msg Message) (any, error) {
+		fields, err := registryFields(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		s := c.server
+
+		s.mu.Lock()
+		r := s.registries[name]
+		id, _ := fields[registryKey(r)].(string)
+		_, exists := r.items[id]
+		delete(r.items, id)
+		s.mu.Unlock()
+
+		if !exists {
+			return nil, Error{Code: "not_found", Message: fmt.Sprintf("%s not found", name)}
+		}
+
+		s.FireEvent(r.event, map[string]any{"action": "remove", r.idKey: id})
+
+		return nil, nil
+	}
+}
+
+func handleDeviceRemoveConfigEntry(c *Conn, msg Message) (any, error) {
+	var request struct {
+		DeviceID      string `json:"device_id"`
+		ConfigEntryID string `json:"config_entry_id"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	s := c.server
+
+	s.mu.Lock()
+	r := s.registries["device"]
+
+	device, exists := r.items[request.DeviceID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, Error{Code: "not_found", Message: "Unknown device"}
+	}
+
+	entries, _ := device["config_entries"].([]any)
+	remaining := make([]any, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry != request.ConfigEntryID {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	device["config_entries"] = remaining
+	updated := maps.Clone(device)
+
+	action := "update"
+	if len(remaining) == 0 {
+		action = "remove"
+		updated = nil
+
+		delete(r.items, request.DeviceID)
+	}
+	s.mu.Unlock()
+
+	s.FireEvent(r.event, map[string]any{"action": action, "device_id": request.DeviceID})
+
+	return updated, nil
+}
+
+// Decode a registry command into its fields, without id and type.
+func registryFields(msg Message) (map[string]any, error) {
+	var fields map[string]any
+	if err := msg.Decode(&fields); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	delete(fields, "id")
+	delete(fields, "type")
+
+	return fields, nil
+}
+
+// The key commands use to reference an item, which for devices differs from
+// the key in the item itself.
+func registryKey(r *registry) string {
+	if r.idKey == "id" {
+		return "device_id"
+	}
+
+	return r.idKey
+}
+
+func sortedIDs(items map[string]map[string]any) []string {
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Generate an ID from a name the way Home Assistant does, such as living_room
+// for "Living Room".
+func slug(name string) string {
+	var b strings.Builder
+
+	underscore := false
+
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+
+			underscore = false
+
+			continue
+		}
+
+		if !underscore && b.Len() > 0 {
+			b.WriteRune('_')
+
+			underscore = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "_")
+}
@@ -0,0 +1,457 @@
+package hatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+func (s *Server) serveREST(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeJSON(w, http.StatusUnauthorized, message("Unauthorized"))
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/")
+	resource, rest, _ := strings.Cut(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		writeJSON(w, http.StatusOK, message("API running."))
+	case r.Method == http.MethodGet && path == "config":
+		s.mu.Lock()
+		cfg := s.config
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, cfg)
+	case r.Method == http.MethodGet && path == "events":
+		s.serveEvents(w)
+	case r.Method == http.MethodPost && resource == "events":
+		s.serveFireEvent(w, r, rest)
+	case r.Method == http.MethodGet && path == "services":
+		s.serveServices(w)
+	case r.Method == http.MethodPost && path == "config/core/check_config":
+		writeJSON(w, http.StatusOK, map[string]any{"result": "valid", "errors": nil, "warnings": nil})
+	case r.Method == http.MethodPost && resource == "services":
+		s.serveCallService(w, r, rest)
+	case r.Method == http.MethodGet && path == "states":
+		writeJSON(w, http.StatusOK, s.sortedStates())
+	case resource == "states":
+		s.serveState(w, r, rest)
+	case r.Method == http.MethodGet && resource == "history":
+		s.serveHistory(w, r, strings.TrimPrefix(strings.TrimPrefix(rest, "period"), "/"))
+	case r.Method == http.MethodGet && resource == "logbook":
+		s.serveLogbook(w, r, rest)
+	case r.Method == http.MethodGet && path == "error_log":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && resource == "camera_proxy":
+		s.serveCameraProxy(w, rest)
+	case r.Method == http.MethodGet && resource == "calendars":
+		s.serveCalendars(w, rest)
+	case r.Method == http.MethodPost && path == "template":
+		s.serveTemplate(w, r)
+	case r.Method == http.MethodGet && path == "stream":
+		s.serveStream(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, message("Not found"))
+	}
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter) {
+	listeners := map[string]int{"state_changed": 0}
+
+	for _, conn := range s.connections() {
+		conn.mu.Lock()
+		for _, sub := range conn.subscriptions {
+			if !sub.trigger && !sub.custom && sub.eventType != "" {
+				listeners[sub.eventType]++
+			}
+		}
+		conn.mu.Unlock()
+	}
+
+	events := make([]map[string]any, 0, len(listeners))
+	for event, count := range listeners {
+		events = append(events, map[string]any{"event": event, "listener_count": count})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i]["event"].(string) < events[j]["event"].(string)
+	})
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *Server) serveFireEvent(w http.ResponseWriter, r *http.Request, eventType string) {
+	var data map[string]any
+	if err := decodeBody(r, &data); err != nil {
+		writeJSON(w, http.StatusBadRequest, message("Event data should be valid JSON."))
+		return
+	}
+
+	s.FireEvent(eventType, data)
+	writeJSON(w, http.StatusOK, message(fmt.Sprintf("Event %s fired.", eventType)))
+}
+
+func (s *Server) serveServices(w http.ResponseWriter) {
+	s.mu.Lock()
+	services := make([]map[string]any, 0, len(s.services))
+
+	for domain, domainServices := range s.services {
+		services = append(services, map[string]any{"domain": domain, "services": domainServices})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i]["domain"].(string) < services[j]["domain"].(string)
+	})
+
+	writeJSON(w, http.StatusOK, services)
+}
+
+func (s *Server) serveCallService(w http.ResponseWriter, r *http.Request, path string) {
+	domain, service, ok := strings.Cut(path, "/")
+	if !ok {
+		writeJSON(w, http.StatusNotFound, message("Service not found."))
+		return
+	}
+
+	var data map[string]any
+	if err := decodeBody(r, &data); err != nil {
+		writeJSON(w, http.StatusBadRequest, message("Data should be valid JSON."))
+		return
+	}
+
+	call := ServiceCall{
+		Domain:         domain,
+		Service:        service,
+		ServiceData:    data,
+		ReturnResponse: r.URL.Query().Has("return_response"),
+	}
+
+	response, err := s.callService(call)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, message(err.Error()))
+		return
+	}
+
+	if call.ReturnResponse {
+		writeJSON(w, http.StatusOK, map[string]any{"changed_states": []types.Entity{}, "service_response": response})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, []types.Entity{})
+}
+
+func (s *Server) serveState(w http.ResponseWriter, r *http.Request, entityID string) {
+	switch r.Method {
+	case http.MethodGet:
+		e, exists := s.State(entityID)
+		if !exists {
+			writeJSON(w, http.StatusNotFound, message("Entity not found."))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, e)
+	case http.MethodPost:
+		if _, err := entity.Parse(entityID); err != nil {
+			writeJSON(w, http.StatusBadRequest, message("Invalid entity ID specified."))
+			return
+		}
+
+		var request struct {
+			State      string         `json:"state"`
+			Attributes map[string]any `json:"attributes"`
+		}
+
+		if err := decodeBody(r, &request); err != nil {
+			writeJSON(w, http.StatusBadRequest, message("Invalid JSON specified."))
+			return
+		}
+
+		_, existed := s.State(entityID)
+		e := s.SetState(entityID, request.State, request.Attributes)
+
+		if existed {
+			writeJSON(w, http.StatusOK, e)
+			return
+		}
+
+		w.Header().Set("Location", "/api/states/"+entityID)
+		writeJSON(w, http.StatusCreated, e)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, message("Method not allowed."))
+	}
+}
+
+// Serve /api/history/period in the [][]state format, one list per entity.
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request, timestamp string) {
+	start, end, ok := timeRange(w, r, timestamp)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	minimal := query.Has("minimal_response")
+	noAttributes := query.Has("no_attributes")
+
+	result := [][]map[string]any{}
+
+	for _, entityID := range s.historyEntities(query.Get("filter_entity_id")) {
+		var rows []map[string]any
+
+		for _, e := range s.historyBetween(entityID, start, end) {
+			row := map[string]any{"state": e.State, "last_changed": e.LastChanged}
+
+			if !minimal || len(rows) == 0 {
+				row["entity_id"] = e.EntityID
+				row["last_updated"] = e.LastUpdated
+
+				if !noAttributes {
+					row["attributes"] = e.Attributes
+				}
+			}
+
+			rows = append(rows, row)
+		}
+
+		if len(rows) > 0 {
+			result = append(result, rows)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Serve /api/logbook with an entry for every recorded state change.
+func (s *Server) serveLogbook(w http.ResponseWriter, r *http.Request, timestamp string) {
+	start, end, ok := timeRange(w, r, timestamp)
+	if !ok {
+		return
+	}
+
+	entries := []map[string]any{}
+
+	for _, entityID := range s.historyEntities(r.URL.Query().Get("entity")) {
+		for _, e := range s.historyBetween(entityID, start, end) {
+			entries = append(entries, map[string]any{
+				"domain":    e.EntityID.Domain(),
+				"entity_id": e.EntityID,
+				"message":   "changed to " + e.State.String(),
+				"name":      e.EntityID.Name(),
+				"when":      e.LastChanged,
+			})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) serveCameraProxy(w http.ResponseWriter, entityID string) {
+	if _, exists := s.State(entityID); !exists {
+		writeJSON(w, http.StatusNotFound, message("Entity not found."))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveCalendars(w http.ResponseWriter, calendarID string) {
+	if calendarID != "" {
+		writeJSON(w, http.StatusOK, []any{})
+		return
+	}
+
+	calendars := []map[string]any{}
+
+	for _, e := range s.sortedStates() {
+		if e.EntityID.Domain() == domains.Calendar {
+			calendars = append(calendars, map[string]any{"entity_id": e.EntityID, "name": e.EntityID.Name()})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, calendars)
+}
+
+func (s *Server) serveTemplate(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Template  string         `json:"template"`
+		Variables map[string]any `json:"variables"`
+	}
+
+	if err := decodeBody(r, &request); err != nil {
+		writeJSON(w, http.StatusBadRequest, message("Invalid JSON specified."))
+		return
+	}
+
+	rendered, err := s.renderTemplate(request.Template, request.Variables)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, message("Error rendering template: "+err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, rendered)
+}
+
+// Serve the server-sent event stream. Only events fired after the client
+// connects are sent, filtered by the restrict query parameter.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, message("Streaming unsupported."))
+		return
+	}
+
+	var restrict map[string]bool
+	if value := r.URL.Query().Get("restrict"); value != "" {
+		restrict = make(map[string]bool)
+		for _, eventType := range strings.Split(value, ",") {
+			restrict[eventType] = true
+		}
+	}
+
+	events := make(chan types.Event, 16)
+
+	s.mu.Lock()
+	s.streams[events] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, events)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, "data: ping\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-events:
+			if restrict != nil && !restrict[event.EventType] {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Return the entity IDs in a comma separated filter, or every entity with history.
+func (s *Server) historyEntities(filter string) []entity.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []entity.ID
+
+	if filter != "" {
+		for _, raw := range strings.Split(filter, ",") {
+			if id, err := entity.Parse(raw); err == nil {
+				ids = append(ids, id)
+			}
+		}
+
+		return ids
+	}
+
+	for id := range s.history {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+
+	return ids
+}
+
+func (s *Server) historyBetween(id entity.ID, start, end time.Time) []types.Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var states []types.Entity
+
+	for _, e := range s.history[id] {
+		if e.LastUpdated.Before(start) || e.LastUpdated.After(end) {
+			continue
+		}
+
+		states = append(states, e)
+	}
+
+	return states
+}
+
+// Parse the start time from the path and the end_time query parameter. The
+// start defaults to one day ago and the end to one day after the start.
+func timeRange(w http.ResponseWriter, r *http.Request, timestamp string) (time.Time, time.Time, bool) {
+	start := time.Now().Add(-24 * time.Hour)
+
+	if timestamp != "" {
+		parsed, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, message("Invalid datetime"))
+			return time.Time{}, time.Time{}, false
+		}
+
+		start = parsed
+	}
+
+	end := start.Add(24 * time.Hour)
+
+	if value := r.URL.Query().Get("end_time"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, message("Invalid end_time"))
+			return time.Time{}, time.Time{}, false
+		}
+
+		end = parsed
+	}
+
+	return start, end, true
+}
+
+func decodeBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func message(msg string) map[string]string {
+	return map[string]string{"message": msg}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
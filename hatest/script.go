@@ -0,0 +1,150 @@
+package hatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Only the keys that Home Assistant requires are checked: triggers need a
+// platform and conditions a condition type. Actions must be objects.
+func handleValidateConfig(_ *Conn, msg Message) (any, error) {
+	var request struct {
+		Trigger   json.RawMessage `json:"trigger"`
+		Condition json.RawMessage `json:"condition"`
+		Action    json.RawMessage `json:"action"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	result := make(map[string]any)
+
+	for name, section := range map[string]struct {
+		raw      json.RawMessage
+		required []string
+	}{
+		"trigger":   {request.Trigger, []string{"platform", "trigger"}},
+		"condition": {request.Condition, []string{"condition"}},
+		"action":    {request.Action, nil},
+	} {
+		if len(section.raw) == 0 {
+			continue
+		}
+
+		result[name] = validateSection(section.raw, section.required)
+	}
+
+	return result, nil
+}
+
+// Check that every item of a section has one of the required keys.
+func validateSection(raw json.RawMessage, required []string) map[string]any {
+	var items []map[string]any
+	if err := decodeList(raw, &items); err != nil {
+		return map[string]any{"valid": false, "error": "expected a dictionary"}
+	}
+
+	for _, item := range items {
+		if len(required) == 0 {
+			continue
+		}
+
+		found := false
+
+		for _, key := range required {
+			if _, exists := item[key]; exists {
+				found = true
+			}
+		}
+
+		if !found {
+			return map[string]any{"valid": false, "error": "required key not provided @ data['" + required[0] + "']"}
+		}
+	}
+
+	return map[string]any{"valid": true, "error": nil}
+}
+
+// Runs the service calls in the sequence in order. A service call's response is
+// stored in its response_variable, and a stop step with a response_variable
+// ends the script with that variable as the response. Other steps are skipped.
+func handleExecuteScript(c *Conn, msg Message) (any, error) {
+	var request struct {
+		Sequence  json.RawMessage `json:"sequence"`
+		Variables map[string]any  `json:"variables"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	var steps []struct {
+		Action           string              `json:"action"`
+		Service          string              `json:"service"` // Name of action before 2024.8
+		Data             map[string]any      `json:"data"`
+		Target           types.ServiceTarget `json:"target"`
+		ResponseVariable string              `json:"response_variable"`
+		Stop             *string             `json:"stop"`
+	}
+
+	if err := decodeList(request.Sequence, &steps); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	variables := make(map[string]any)
+	for key, value := range request.Variables {
+		variables[key] = value
+	}
+
+	var response any
+
+	for _, step := range steps {
+		if step.Stop != nil {
+			if step.ResponseVariable != "" {
+				response = variables[step.ResponseVariable]
+			}
+
+			break
+		}
+
+		action := step.Action
+		if action == "" {
+			action = step.Service
+		}
+
+		domain, service, ok := strings.Cut(action, ".")
+		if !ok {
+			continue
+		}
+
+		result, err := c.server.callService(ServiceCall{
+			Domain:         domain,
+			Service:        service,
+			ServiceData:    step.Data,
+			Target:         step.Target,
+			ReturnResponse: step.ResponseVariable != "",
+		})
+		if err != nil {
+			return nil, Error{Code: "home_assistant_error", Message: err.Error()}
+		}
+
+		if step.ResponseVariable != "" {
+			variables[step.ResponseVariable] = result
+		}
+	}
+
+	return map[string]any{"context": types.Context{ID: newContextID()}, "response": response}, nil
+}
+
+// Decode a JSON array, or a single object as a list of one.
+func decodeList(raw json.RawMessage, v any) error {
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		raw = append(append([]byte{'['}, trimmed...), ']')
+	}
+
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,501 @@
+// An in-process fake Home Assistant for testing code built on the rest and
+// websocket clients without a live instance.
+
+package hatest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/config"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/version"
+)
+
+type (
+	// Server speaks enough of the Home Assistant websocket and REST APIs to
+	// back the clients in this module. Entity states are scriptable and
+	// changes are pushed to state_changed subscribers.
+	Server struct {
+		*httptest.Server
+		Token   string
+		Version string
+
+		mu              sync.Mutex
+		states          map[entity.ID]types.Entity
+		history         map[entity.ID][]types.Entity
+		entityRegistry  map[entity.ID]types.EntityRegistryEntryExtended
+		registries      map[string]*registry
+		config          types.Config
+		services        types.Services
+		panels          types.Panels
+		statistics      map[string]*statistics
+		conns           map[*Conn]struct{}
+		streams         map[chan types.Event]struct{}
+		received        []Message
+		serviceCalls    []ServiceCall
+		serviceHandlers map[string]ServiceHandler
+		handlers        map[string]CommandHandler
+		templateHandler func(template string, variables map[string]any) (string, error)
+		authHold        chan struct{} // Closed by ReleaseAuth
+		upgrader        websocket.Upgrader
+	}
+
+	Option func(*Server)
+
+	// ServiceCall is a service call received over either API.
+	ServiceCall struct {
+		Domain         string              `json:"domain"`
+		Service        string              `json:"service"`
+		ServiceData    map[string]any      `json:"service_data"`
+		Target         types.ServiceTarget `json:"target"`
+		ReturnResponse bool                `json:"return_response"`
+	}
+
+	// ServiceHandler handles calls to a service. The returned value is sent as
+	// the service response.
+	ServiceHandler func(call ServiceCall) (any, error)
+
+	// CommandHandler handles a websocket command. The returned value is sent as
+	// the result. Handlers for subscriptions can push events with Conn.SendEvent.
+	CommandHandler func(conn *Conn, msg Message) (any, error)
+
+	// Message is a websocket command received from a client.
+	Message struct {
+		ID   int64           `json:"id"`
+		Type string          `json:"type"`
+		Raw  json.RawMessage `json:"-"`
+	}
+
+	// Error is returned by handlers to send a failed result.
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+)
+
+// ErrNoResult can be returned by a CommandHandler to send no result at all,
+// simulating a command that never completes.
+var ErrNoResult = errors.New("hatest: no result")
+
+func (e Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// WithVersion sets the version reported during authentication and by get_config.
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.Version = version
+	}
+}
+
+// WithToken sets the access token clients must authenticate with.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.Token = token
+	}
+}
+
+// WithStates loads the initial entity states.
+func WithStates(entities ...types.Entity) Option {
+	return func(s *Server) {
+		for _, e := range entities {
+			s.states[e.EntityID] = e
+		}
+	}
+}
+
+// WithServices sets the services returned by get_services.
+func WithServices(services types.Services) Option {
+	return func(s *Server) {
+		s.services = services
+	}
+}
+
+// NewServer starts a fake Home Assistant that is closed when the test ends.
+func NewServer(tb testing.TB, options ...Option) *Server {
+	tb.Helper()
+
+	s := &Server{
+		Token:           "test-token",
+		Version:         "2024.12.0",
+		states:          make(map[entity.ID]types.Entity),
+		history:         make(map[entity.ID][]types.Entity),
+		entityRegistry:  make(map[entity.ID]types.EntityRegistryEntryExtended),
+		registries:      newRegistries(),
+		services:        make(types.Services),
+		panels:          make(types.Panels),
+		statistics:      make(map[string]*statistics),
+		conns:           make(map[*Conn]struct{}),
+		streams:         make(map[chan types.Event]struct{}),
+		serviceHandlers: make(map[string]ServiceHandler),
+		handlers:        make(map[string]CommandHandler),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	for id, e := range s.states {
+		s.history[id] = append(s.history[id], e)
+	}
+
+	haVersion, err := version.Parse(s.Version)
+	if err != nil {
+		tb.Fatalf("invalid version: %v", err)
+	}
+
+	s.config = types.Config{
+		LocationName: "Home",
+		TimeZone:     "UTC",
+		State:        config.StateRunning,
+		Components:   []string{"api", "websocket_api"},
+		Version:      haVersion,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/websocket", s.serveWebsocket)
+	mux.HandleFunc("/api/", s.serveREST)
+
+	s.Server = httptest.NewServer(mux)
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// Host returns the host and port to pass to NewClient.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Close disconnects every client and shuts the server down.
+func (s *Server) Close() {
+	s.ReleaseAuth()
+	s.Disconnect()
+	s.Server.Close()
+}
+
+// Disconnect closes every websocket connection, simulating a restart.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.ws.Close()
+	}
+}
+
+// HoldAuth stalls new connections after the client sent its access token, so
+// that it is left waiting for the auth result until ReleaseAuth is called.
+func (s *Server) HoldAuth() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authHold == nil {
+		s.authHold = make(chan struct{})
+	}
+}
+
+// ReleaseAuth lets connections held by HoldAuth finish authenticating.
+func (s *Server) ReleaseAuth() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authHold != nil {
+		close(s.authHold)
+		s.authHold = nil
+	}
+}
+
+// SetConfig replaces the config returned by get_config and /api/config.
+func (s *Server) SetConfig(cfg types.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config = cfg
+}
+
+// SetCoreState changes the state reported by get_config and fires the event
+// Home Assistant fires when entering it, such as homeassistant_started.
+func (s *Server) SetCoreState(coreState config.HassConfigState) {
+	s.mu.Lock()
+	s.config.State = coreState
+	s.mu.Unlock()
+
+	switch coreState {
+	case config.StateStarting:
+		s.FireEvent("homeassistant_start", nil)
+	case config.StateRunning:
+		s.FireEvent("homeassistant_started", nil)
+	case config.StateStopping:
+		s.FireEvent("homeassistant_stop", nil)
+	case config.StateFinalWrite:
+		s.FireEvent("homeassistant_final_write", nil)
+	}
+}
+
+// SetServices replaces the services returned by get_services and /api/services.
+func (s *Server) SetServices(services types.Services) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.services = services
+}
+
+// State returns the current state of an entity.
+func (s *Server) State(entityID string) (types.Entity, bool) {
+	id, err := entity.Parse(entityID)
+	if err != nil {
+		return types.Entity{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.states[id]
+
+	return e, exists
+}
+
+// SetState sets the state and attributes of an entity, creating it if needed,
+// and pushes a state_changed event to subscribers.
+func (s *Server) SetState(entityID, value string, attributes map[string]any) types.Entity {
+	id, err := entity.Parse(entityID)
+	if err != nil {
+		panic(err)
+	}
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	rawAttributes, err := json.Marshal(attributes)
+	if err != nil {
+		panic(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond) // Home Assistant timestamps have microsecond precision
+
+	s.mu.Lock()
+	oldState, existed := s.states[id]
+	newState := types.Entity{
+		EntityID:     id,
+		State:        state.Value(value),
+		Attributes:   rawAttributes,
+		LastChanged:  now,
+		LastUpdated:  now,
+		LastReported: now,
+		Context:      types.Context{ID: newContextID()},
+	}
+
+	if existed && oldState.State == newState.State {
+		newState.LastChanged = oldState.LastChanged
+	}
+
+	s.states[id] = newState
+	s.history[id] = append(s.history[id], newState)
+	s.mu.Unlock()
+
+	change := types.StateChange{EntityID: id, NewState: &newState}
+	if existed {
+		change.OldState = &oldState
+	}
+
+	s.FireEvent("state_changed", change)
+	s.pushEntities(change)
+	s.pushTemplates()
+	s.pushStreams(change)
+
+	return newState
+}
+
+// RemoveState removes an entity and pushes a state_changed event with no new state.
+func (s *Server) RemoveState(entityID string) {
+	id, err := entity.Parse(entityID)
+	if err != nil {
+		panic(err)
+	}
+
+	s.mu.Lock()
+	oldState, existed := s.states[id]
+	delete(s.states, id)
+	s.mu.Unlock()
+
+	if existed {
+		change := types.StateChange{EntityID: id, OldState: &oldState}
+
+		s.FireEvent("state_changed", change)
+		s.pushEntities(change)
+		s.pushTemplates()
+	}
+}
+
+// FireEvent pushes an event to every connection subscribed to its type and to
+// every /api/stream client.
+func (s *Server) FireEvent(eventType string, data any) {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+
+	event := types.Event{
+		EventBase: types.EventBase{
+			Origin:    "LOCAL",
+			TimeFired: time.Now().UTC(),
+			Context:   types.Context{ID: newContextID()},
+		},
+		EventType: eventType,
+		Data:      rawData,
+	}
+
+	for _, conn := range s.connections() {
+		conn.pushEvent(event)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for stream := range s.streams {
+		select {
+		case stream <- event:
+		default:
+		}
+	}
+}
+
+// FireTrigger pushes trigger variables to every subscribe_trigger subscription.
+// variables is sent as the "trigger" variable.
+func (s *Server) FireTrigger(variables any) {
+	for _, conn := range s.connections() {
+		conn.pushTrigger(variables)
+	}
+}
+
+// HandleService registers a handler for calls to domain.service.
+func (s *Server) HandleService(domain, service string, handler ServiceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.serviceHandlers[domain+"."+service] = handler
+}
+
+// HandleTemplate registers the function used to render templates. By default
+// templates are returned unrendered.
+func (s *Server) HandleTemplate(handler func(template string, variables map[string]any) (string, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.templateHandler = handler
+}
+
+// Handle registers a handler for a websocket command type, replacing the
+// built-in one if there is one.
+func (s *Server) Handle(msgType string, handler CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[msgType] = handler
+}
+
+// ServiceCalls returns every service call received so far.
+func (s *Server) ServiceCalls() []ServiceCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]ServiceCall(nil), s.serviceCalls...)
+}
+
+// Messages returns every websocket command of the given type received so far.
+func (s *Server) Messages(msgType string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []Message
+
+	for _, msg := range s.received {
+		if msg.Type == msgType {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Return the states sorted by entity ID.
+func (s *Server) sortedStates() types.Entities {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entities := make(types.Entities, 0, len(s.states))
+	for _, e := range s.states {
+		entities = append(entities, e)
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].EntityID.String() < entities[j].EntityID.String()
+	})
+
+	return entities
+}
+
+// Record a service call and run its handler if one is registered.
+func (s *Server) callService(call ServiceCall) (any, error) {
+	s.mu.Lock()
+	s.serviceCalls = append(s.serviceCalls, call)
+	handler := s.serviceHandlers[call.Domain+"."+call.Service]
+	s.mu.Unlock()
+
+	if handler == nil {
+		return nil, nil
+	}
+
+	return handler(call)
+}
+
+func (s *Server) renderTemplate(template string, variables map[string]any) (string, error) {
+	s.mu.Lock()
+	handler := s.templateHandler
+	s.mu.Unlock()
+
+	if handler == nil {
+		return template, nil
+	}
+
+	return handler(template, variables)
+}
+
+func (s *Server) connections() []*Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+var contextCounter atomic.Int64
+
+// Generate a unique context ID, formatted like the 26 character IDs Home Assistant uses.
+func newContextID() string {
+	return fmt.Sprintf("%026d", contextCounter.Add(1))
+}
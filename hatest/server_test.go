@@ -0,0 +1,74 @@
+package hatest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/hatest"
+	"github.com/ryanjohnsontv/go-homeassistant/rest"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestREST(t *testing.T) {
+	ctx := context.Background()
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "on", map[string]any{"brightness": 255})
+
+	client, err := rest.NewClient(server.URL, server.Token)
+	require.NoError(t, err)
+
+	t.Run("GetHealth", func(t *testing.T) {
+		assert.NoError(t, client.GetHealth(ctx))
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		unauthorized, err := rest.NewClient(server.URL, "wrong-token")
+		require.NoError(t, err)
+		assert.Error(t, unauthorized.GetHealth(ctx))
+	})
+
+	t.Run("GetConfig", func(t *testing.T) {
+		config, err := client.GetConfig(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Home", config.LocationName)
+	})
+
+	t.Run("GetState", func(t *testing.T) {
+		e, err := client.GetState(ctx, "light.kitchen")
+		require.NoError(t, err)
+		assert.Equal(t, state.Value("on"), e.State)
+	})
+
+	t.Run("UpsertState", func(t *testing.T) {
+		e, location, err := client.UpsertState(ctx, rest.UpsertStateRequest{EntityID: "sensor.new", State: "12"})
+		require.NoError(t, err)
+		assert.Equal(t, state.Value("12"), e.State)
+		assert.Equal(t, "/api/states/sensor.new", location.String())
+
+		stored, exists := server.State("sensor.new")
+		assert.True(t, exists)
+		assert.Equal(t, state.Value("12"), stored.State)
+	})
+
+	t.Run("CallService", func(t *testing.T) {
+		_, err := client.CallService(ctx, domains.Light, "turn_off", map[string]any{"entity_id": "light.kitchen"})
+		require.NoError(t, err)
+
+		calls := server.ServiceCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "light.kitchen", calls[0].ServiceData["entity_id"])
+	})
+
+	t.Run("RenderTemplate", func(t *testing.T) {
+		server.HandleTemplate(func(string, map[string]any) (string, error) {
+			return "rendered", nil
+		})
+
+		rendered, err := client.RenderTemplate(ctx, rest.Template{Template: "{{ states('light.kitchen') }}"})
+		require.NoError(t, err)
+		assert.Equal(t, "rendered", rendered)
+	})
+}
@@ -0,0 +1,362 @@
+package hatest
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+type (
+	// Statistics imported with recorder/import_statistics, which are the only
+	// statistics the fake recorder keeps.
+	statistics struct {
+		metadata statisticMetadata
+		rows     []statisticRow // Hourly, sorted by start
+	}
+
+	statisticMetadata struct {
+		StatisticID       string  `json:"statistic_id"`
+		Source            string  `json:"source"`
+		Name              *string `json:"name"`
+		UnitOfMeasurement *string `json:"unit_of_measurement"`
+		UnitClass         *string `json:"unit_class"`
+		HasMean           bool    `json:"has_mean"`
+		MeanType          *int    `json:"mean_type"`
+		HasSum            bool    `json:"has_sum"`
+	}
+
+	statisticRow struct {
+		Start     time.Time  `json:"start"`
+		Mean      *float64   `json:"mean"`
+		Min       *float64   `json:"min"`
+		Max       *float64   `json:"max"`
+		State     *float64   `json:"state"`
+		Sum       *float64   `json:"sum"`
+		LastReset *time.Time `json:"last_reset"`
+	}
+)
+
+// Unit classes of the units the fake recorder knows how to classify.
+var unitClasses = map[string]string{
+	"Wh":  "energy",
+	"kWh": "energy",
+	"MWh": "energy",
+	"°C":  "temperature",
+	"°F":  "temperature",
+	"W":   "power",
+	"kW":  "power",
+}
+
+func handleImportStatistics(c *Conn, msg Message) (any, error) {
+	var request struct {
+		Metadata statisticMetadata `json:"metadata"`
+		Stats    []statisticRow    `json:"stats"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	metadata := request.Metadata
+
+	if metadata.Source == "recorder" {
+		if _, err := entity.Parse(metadata.StatisticID); err != nil {
+			return nil, Error{Code: "invalid_format", Message: "Invalid statistic_id"}
+		}
+	} else if !strings.HasPrefix(metadata.StatisticID, metadata.Source+":") {
+		return nil, Error{Code: "invalid_format", Message: "Invalid statistic_id"}
+	}
+
+	if metadata.UnitClass != nil {
+		if metadata.UnitOfMeasurement == nil || unitClasses[*metadata.UnitOfMeasurement] != *metadata.UnitClass {
+			return nil, Error{Code: "home_assistant_error", Message: "Unsupported unit_class: '" + *metadata.UnitClass + "'"}
+		}
+	}
+
+	if metadata.MeanType == nil {
+		meanType := 0
+		if metadata.HasMean {
+			meanType = 1
+		}
+
+		metadata.MeanType = &meanType
+	}
+
+	for _, row := range request.Stats {
+		if !row.Start.Equal(row.Start.Truncate(time.Hour)) {
+			return nil, Error{Code: "invalid_format", Message: "Invalid timestamp: timestamps must be from the top of the hour"}
+		}
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	stats, exists := c.server.statistics[metadata.StatisticID]
+	if !exists {
+		stats = &statistics{}
+		c.server.statistics[metadata.StatisticID] = stats
+	}
+
+	stats.metadata = metadata
+
+	for _, row := range request.Stats {
+		stats.rows = slices.DeleteFunc(stats.rows, func(r statisticRow) bool {
+			return r.Start.Equal(row.Start)
+		})
+		stats.rows = append(stats.rows, row)
+	}
+
+	sort.Slice(stats.rows, func(i, j int) bool {
+		return stats.rows[i].Start.Before(stats.rows[j].Start)
+	})
+
+	return nil, nil
+}
+
+func handleListStatisticIDs(c *Conn, msg Message) (any, error) {
+	var request struct {
+		StatisticType string `json:"statistic_type"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	return c.server.statisticsMetadata(func(metadata statisticMetadata) bool {
+		switch request.StatisticType {
+		case "mean":
+			return metadata.HasMean
+		case "sum":
+			return metadata.HasSum
+		default:
+			return true
+		}
+	}), nil
+}
+
+func handleGetStatisticsMetadata(c *Conn, msg Message) (any, error) {
+	var request struct {
+		StatisticIDs []string `json:"statistic_ids"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	return c.server.statisticsMetadata(func(metadata statisticMetadata) bool {
+		return len(request.StatisticIDs) == 0 || slices.Contains(request.StatisticIDs, metadata.StatisticID)
+	}), nil
+}
+
+// Hourly rows are grouped into the requested period in UTC. Short term
+// statistics are never imported, so the 5minute period is always empty.
+func handleStatisticsDuringPeriod(c *Conn, msg Message) (any, error) {
+	var request struct {
+		StartTime    time.Time  `json:"start_time"`
+		EndTime      *time.Time `json:"end_time"`
+		StatisticIDs []string   `json:"statistic_ids"`
+		Period       string     `json:"period"`
+		Types        []string   `json:"types"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	if !slices.Contains([]string{"5minute", "hour", "day", "week", "month"}, request.Period) {
+		return nil, Error{Code: "invalid_format", Message: "Invalid period"}
+	}
+
+	end := time.Now()
+	if request.EndTime != nil {
+		end = *request.EndTime
+	}
+
+	result := make(map[string][]map[string]any)
+
+	if request.Period == "5minute" {
+		return result, nil
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	for _, id := range request.StatisticIDs {
+		stats, exists := c.server.statistics[id]
+		if !exists {
+			continue
+		}
+
+		if periods := stats.during(request.StartTime, end, request.Period, request.Types); len(periods) > 0 {
+			result[id] = periods
+		}
+	}
+
+	return result, nil
+}
+
+// Return the metadata of the statistics that match, sorted by ID.
+func (s *Server) statisticsMetadata(match func(statisticMetadata) bool) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []map[string]any{}
+
+	for _, stats := range s.statistics {
+		metadata := stats.metadata
+		if !match(metadata) {
+			continue
+		}
+
+		unitClass := metadata.UnitClass
+		if unitClass == nil && metadata.UnitOfMeasurement != nil {
+			if class, exists := unitClasses[*metadata.UnitOfMeasurement]; exists {
+				unitClass = &class
+			}
+		}
+
+		list = append(list, map[string]any{
+			"statistic_id":                   metadata.StatisticID,
+			"source":                         metadata.Source,
+			"name":                           metadata.Name,
+			"has_mean":                       metadata.HasMean,
+			"has_sum":                        metadata.HasSum,
+			"statistics_unit_of_measurement": metadata.UnitOfMeasurement,
+			"display_unit_of_measurement":    metadata.UnitOfMeasurement,
+			"unit_class":                     unitClass,
+			"mean_type":                      metadata.MeanType,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["statistic_id"].(string) < list[j]["statistic_id"].(string)
+	})
+
+	return list
+}
+
+// Group the rows starting between start and end by period. The change of a
+// period is its last sum minus the last sum before it.
+func (stats *statistics) during(start, end time.Time, period string, types []string) []map[string]any {
+	var (
+		periods []map[string]any
+		current map[string]any
+		count   int
+		total   float64
+		lastSum float64
+		prevSum float64
+	)
+
+	wanted := func(statType string) bool {
+		return len(types) == 0 || slices.Contains(types, statType)
+	}
+
+	for _, row := range stats.rows {
+		if row.Start.Before(start) {
+			if row.Sum != nil {
+				lastSum = *row.Sum
+			}
+
+			continue
+		}
+
+		if !row.Start.Before(end) {
+			break
+		}
+
+		periodStart := startOfPeriod(row.Start, period)
+
+		if current == nil || current["start"] != unixMillis(periodStart) {
+			current = map[string]any{
+				"start": unixMillis(periodStart),
+				"end":   unixMillis(endOfPeriod(periodStart, period)),
+			}
+			periods = append(periods, current)
+			count, total, prevSum = 0, 0, lastSum
+		}
+
+		if stats.metadata.HasMean {
+			if row.Mean != nil {
+				count++
+				total += *row.Mean
+
+				if wanted("mean") {
+					current["mean"] = total / float64(count)
+				}
+			}
+
+			if row.Min != nil && wanted("min") {
+				if low, ok := current["min"].(float64); !ok || *row.Min < low {
+					current["min"] = *row.Min
+				}
+			}
+
+			if row.Max != nil && wanted("max") {
+				if high, ok := current["max"].(float64); !ok || *row.Max > high {
+					current["max"] = *row.Max
+				}
+			}
+		}
+
+		if stats.metadata.HasSum {
+			if row.State != nil && wanted("state") {
+				current["state"] = *row.State
+			}
+
+			if row.Sum != nil {
+				lastSum = *row.Sum
+
+				if wanted("sum") {
+					current["sum"] = *row.Sum
+				}
+
+				if wanted("change") {
+					current["change"] = lastSum - prevSum
+				}
+			}
+
+			if row.LastReset != nil && wanted("last_reset") {
+				current["last_reset"] = unixMillis(*row.LastReset)
+			}
+		}
+	}
+
+	return periods
+}
+
+func startOfPeriod(t time.Time, period string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case "day":
+		return day
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7) // Weeks start on Monday
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return t.Truncate(time.Hour)
+	}
+}
+
+func endOfPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case "day":
+		return start.AddDate(0, 0, 1)
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.Add(time.Hour)
+	}
+}
+
+// Timestamps in statistics are sent as milliseconds since the epoch.
+func unixMillis(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e3
+}
@@ -0,0 +1,67 @@
+package hatest
+
+type renderTemplateRequest struct {
+	Template     string         `json:"template"`
+	Variables    map[string]any `json:"variables"`
+	ReportErrors bool           `json:"report_errors"`
+}
+
+// Templates are rendered with the handler set by Server.HandleTemplate. As the
+// referenced entities aren't known they are rendered again on every state
+// change and report listening to all states.
+func handleRenderTemplate(c *Conn, msg Message) (any, error) {
+	var request renderTemplateRequest
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	result, err := c.server.renderTemplate(request.Template, request.Variables)
+	if err != nil {
+		return nil, Error{Code: "template_error", Message: err.Error()}
+	}
+
+	c.mu.Lock()
+	c.subscriptions[msg.ID] = subscription{render: &request}
+	c.mu.Unlock()
+
+	c.SendResult(msg.ID, nil)
+	c.SendEvent(msg.ID, templateRender(result))
+
+	return nil, ErrNoResult
+}
+
+func (s *Server) pushTemplates() {
+	for _, conn := range s.connections() {
+		conn.mu.Lock()
+		renders := make(map[int64]*renderTemplateRequest)
+		for id, sub := range conn.subscriptions {
+			if sub.render != nil {
+				renders[id] = sub.render
+			}
+		}
+		conn.mu.Unlock()
+
+		for id, request := range renders {
+			result, err := s.renderTemplate(request.Template, request.Variables)
+
+			switch {
+			case err == nil:
+				conn.SendEvent(id, templateRender(result))
+			case request.ReportErrors:
+				conn.SendEvent(id, map[string]any{"error": err.Error(), "level": "ERROR"})
+			}
+		}
+	}
+}
+
+func templateRender(result string) map[string]any {
+	return map[string]any{
+		"result": result,
+		"listeners": map[string]any{
+			"all":      true,
+			"entities": []string{},
+			"domains":  []string{},
+			"time":     false,
+		},
+	}
+}
@@ -0,0 +1,390 @@
+package hatest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	// Conn is a websocket connection from a client.
+	Conn struct {
+		server        *Server
+		ws            *websocket.Conn
+		writeMu       sync.Mutex
+		mu            sync.Mutex
+		subscriptions map[int64]subscription
+	}
+
+	subscription struct {
+		events    bool   // subscribe_events
+		eventType string // Empty for all events
+		trigger   bool
+		custom    bool // Created by a handler registered with Server.Handle
+		entities  bool // subscribe_entities, limited to entityIDs if set
+		entityIDs map[entity.ID]bool
+		render    *renderTemplateRequest // render_template, rendered again on every state change
+		stream    *streamRequest         // history/stream or logbook/event_stream
+	}
+)
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	conn := &Conn{
+		server:        s,
+		ws:            ws,
+		subscriptions: make(map[int64]subscription),
+	}
+
+	if !conn.authenticate() {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		msg.Raw = data
+
+		s.mu.Lock()
+		s.received = append(s.received, msg)
+		s.mu.Unlock()
+
+		conn.handle(msg)
+	}
+}
+
+// Run the auth phase, reporting whether the client authenticated.
+func (c *Conn) authenticate() bool {
+	c.send(map[string]any{"type": "auth_required", "ha_version": c.server.Version})
+
+	var auth struct {
+		Type        string `json:"type"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := c.ws.ReadJSON(&auth); err != nil {
+		return false
+	}
+
+	c.server.mu.Lock()
+	hold := c.server.authHold
+	c.server.mu.Unlock()
+
+	if hold != nil {
+		<-hold
+	}
+
+	if auth.Type != "auth" || auth.AccessToken != c.server.Token {
+		c.send(map[string]any{"type": "auth_invalid", "message": "Invalid access token or password"})
+		return false
+	}
+
+	c.send(map[string]any{"type": "auth_ok", "ha_version": c.server.Version})
+
+	return true
+}
+
+func (c *Conn) handle(msg Message) {
+	if msg.Type == "ping" {
+		c.send(map[string]any{"id": msg.ID, "type": "pong"})
+		return
+	}
+
+	c.server.mu.Lock()
+	handler, custom := c.server.handlers[msg.Type]
+	c.server.mu.Unlock()
+
+	if !custom {
+		handler = builtinHandlers[msg.Type]
+	}
+
+	if handler == nil {
+		c.SendError(msg.ID, "unknown_command", "Unknown command.")
+		return
+	}
+
+	result, err := handler(c, msg)
+	if errors.Is(err, ErrNoResult) {
+		return
+	}
+
+	if err != nil {
+		var haErr Error
+		if !errors.As(err, &haErr) {
+			haErr = Error{Code: "unknown_error", Message: err.Error()}
+		}
+
+		c.SendError(msg.ID, haErr.Code, haErr.Message)
+
+		return
+	}
+
+	c.SendResult(msg.ID, result)
+}
+
+// Subscribe marks a command as a subscription so that it can be cancelled
+// with unsubscribe_events. Used by handlers registered with Server.Handle.
+func (c *Conn) Subscribe(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscriptions[id] = subscription{custom: true}
+}
+
+// Subscribed reports whether a subscription is still active.
+func (c *Conn) Subscribed(id int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, exists := c.subscriptions[id]
+
+	return exists
+}
+
+// SendResult sends a successful result for a command.
+func (c *Conn) SendResult(id int64, result any) {
+	c.send(map[string]any{"id": id, "type": "result", "success": true, "result": result})
+}
+
+// SendError sends a failed result for a command.
+func (c *Conn) SendError(id int64, code, message string) {
+	c.send(map[string]any{
+		"id":      id,
+		"type":    "result",
+		"success": false,
+		"error":   Error{Code: code, Message: message},
+	})
+}
+
+// SendEvent sends an event message for a subscription.
+func (c *Conn) SendEvent(id int64, event any) {
+	c.send(map[string]any{"id": id, "type": "event", "event": event})
+}
+
+// Write a message, serialized with every other write on the connection.
+func (c *Conn) send(v any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.ws.WriteJSON(v)
+}
+
+func (c *Conn) pushEvent(event types.Event) {
+	for _, id := range c.matching(func(sub subscription) bool {
+		return sub.events && (sub.eventType == "" || sub.eventType == event.EventType)
+	}) {
+		c.SendEvent(id, event)
+	}
+}
+
+func (c *Conn) pushTrigger(variables any) {
+	for _, id := range c.matching(func(sub subscription) bool {
+		return sub.trigger
+	}) {
+		c.SendEvent(id, map[string]any{
+			"variables": map[string]any{"trigger": variables},
+			"context":   types.Context{ID: newContextID()},
+		})
+	}
+}
+
+func (c *Conn) matching(match func(subscription) bool) []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ids []int64
+
+	for id, sub := range c.subscriptions {
+		if match(sub) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Decode the full command into v.
+func (m Message) Decode(v any) error {
+	return json.Unmarshal(m.Raw, v)
+}
+
+var builtinHandlers = map[string]CommandHandler{
+	"get_states":         handleGetStates,
+	"get_config":         handleGetConfig,
+	"get_services":       handleGetServices,
+	"get_panels":         handleGetPanels,
+	"call_service":       handleCallService,
+	"fire_event":         handleFireEvent,
+	"subscribe_events":   handleSubscribeEvents,
+	"subscribe_trigger":  handleSubscribeTrigger,
+	"subscribe_entities": handleSubscribeEntities,
+	"render_template":    handleRenderTemplate,
+	"validate_config":    handleValidateConfig,
+	"execute_script":     handleExecuteScript,
+
+	"history/history_during_period": handleHistoryDuringPeriod,
+	"history/stream":                handleStream,
+	"logbook/event_stream":          handleStream,
+	"unsubscribe_events":            handleUnsubscribeEvents,
+
+	"recorder/import_statistics":        handleImportStatistics,
+	"recorder/list_statistic_ids":       handleListStatisticIDs,
+	"recorder/get_statistics_metadata":  handleGetStatisticsMetadata,
+	"recorder/statistics_during_period": handleStatisticsDuringPeriod,
+
+	"config/entity_registry/list":   handleEntityRegistryList,
+	"config/entity_registry/get":    handleEntityRegistryGet,
+	"config/entity_registry/update": handleEntityRegistryUpdate,
+	"config/entity_registry/remove": handleEntityRegistryRemove,
+
+	"config/device_registry/list":                handleRegistryList("device"),
+	"config/device_registry/update":              handleRegistryUpdate("device"),
+	"config/device_registry/remove_config_entry": handleDeviceRemoveConfigEntry,
+
+	"config/area_registry/list":   handleRegistryList("area"),
+	"config/area_registry/create": handleRegistryCreate("area"),
+	"config/area_registry/update": handleRegistryUpdate("area"),
+	"config/area_registry/delete": handleRegistryDelete("area"),
+
+	"config/floor_registry/list":   handleRegistryList("floor"),
+	"config/floor_registry/create": handleRegistryCreate("floor"),
+	"config/floor_registry/update": handleRegistryUpdate("floor"),
+	"config/floor_registry/delete": handleRegistryDelete("floor"),
+
+	"config/label_registry/list":   handleRegistryList("label"),
+	"config/label_registry/create": handleRegistryCreate("label"),
+	"config/label_registry/update": handleRegistryUpdate("label"),
+	"config/label_registry/delete": handleRegistryDelete("label"),
+}
+
+func handleGetStates(c *Conn, _ Message) (any, error) {
+	return c.server.sortedStates(), nil
+}
+
+func handleGetConfig(c *Conn, _ Message) (any, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	return c.server.config, nil
+}
+
+func handleGetServices(c *Conn, _ Message) (any, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	return c.server.services, nil
+}
+
+func handleGetPanels(c *Conn, _ Message) (any, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	return c.server.panels, nil
+}
+
+func handleCallService(c *Conn, msg Message) (any, error) {
+	var call ServiceCall
+	if err := msg.Decode(&call); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	response, err := c.server.callService(call)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{"context": types.Context{ID: newContextID()}}
+	if call.ReturnResponse {
+		result["response"] = response
+	}
+
+	return result, nil
+}
+
+func handleFireEvent(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EventType string         `json:"event_type"`
+		EventData map[string]any `json:"event_data"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	c.server.FireEvent(request.EventType, request.EventData)
+
+	return map[string]any{"context": types.Context{ID: newContextID()}}, nil
+}
+
+func handleSubscribeEvents(c *Conn, msg Message) (any, error) {
+	var request struct {
+		EventType string `json:"event_type"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	c.mu.Lock()
+	c.subscriptions[msg.ID] = subscription{events: true, eventType: request.EventType}
+	c.mu.Unlock()
+
+	return nil, nil
+}
+
+func handleSubscribeTrigger(c *Conn, msg Message) (any, error) {
+	c.mu.Lock()
+	c.subscriptions[msg.ID] = subscription{trigger: true}
+	c.mu.Unlock()
+
+	return nil, nil
+}
+
+func handleUnsubscribeEvents(c *Conn, msg Message) (any, error) {
+	var request struct {
+		Subscription int64 `json:"subscription"`
+	}
+
+	if err := msg.Decode(&request); err != nil {
+		return nil, Error{Code: "invalid_format", Message: err.Error()}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.subscriptions[request.Subscription]; !exists {
+		return nil, Error{Code: "not_found", Message: "Subscription not found."}
+	}
+
+	delete(c.subscriptions, request.Subscription)
+
+	return nil, nil
+}
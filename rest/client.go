@@ -11,9 +11,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
@@ -27,6 +30,9 @@ type (
 		bearerToken      string   // Long-Lived Token from Home Assistant
 		httpClient       *http.Client
 		streamHTTPClient *http.Client // Client for event streams
+
+		mu       sync.Mutex
+		services types.Services // Cached to check which services return a response
 	}
 
 	ClientOption func(*Client)
@@ -131,8 +137,50 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
-func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
-	fullURL := c.apiURL.ResolveReference(&url.URL{Path: path}).String()
+// Query parameters of a request. Unset values are left out and flags are sent
+// as a key without a value, which is how Home Assistant checks for them.
+type query map[string]string
+
+func (q query) set(key, value string) {
+	if value != "" {
+		q[key] = value
+	}
+}
+
+func (q query) setTime(key string, t time.Time) {
+	if !t.IsZero() {
+		q[key] = t.Format(time.RFC3339)
+	}
+}
+
+func (q query) setList(key string, values []string) {
+	if len(values) > 0 {
+		q[key] = strings.Join(values, ",")
+	}
+}
+
+func (q query) flag(key string, set bool) {
+	if set {
+		q[key] = ""
+	}
+}
+
+func (q query) encode() string {
+	params := make([]string, 0, len(q))
+
+	for _, key := range slices.Sorted(maps.Keys(q)) {
+		if q[key] == "" {
+			params = append(params, url.QueryEscape(key))
+		} else {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(q[key]))
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, q query, body any) (*http.Request, error) {
+	fullURL := c.apiURL.ResolveReference(&url.URL{Path: path, RawQuery: q.encode()}).String()
 
 	var bodyReader io.Reader
 
@@ -200,7 +248,7 @@ type apiResponse struct {
 
 // GetHealth returns an error if the API is unhealthy.
 func (c *Client) GetHealth(ctx context.Context) error {
-	req, err := c.newRequest(ctx, http.MethodGet, "", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "", nil, nil)
 	if err != nil {
 		return err
 	}
@@ -215,7 +263,7 @@ func (c *Client) GetHealth(ctx context.Context) error {
 
 // GetConfig gets the Home Assistant configuration.
 func (c *Client) GetConfig(ctx context.Context) (types.Config, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "config", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "config", nil, nil)
 	if err != nil {
 		return types.Config{}, err
 	}
@@ -235,7 +283,7 @@ type Event struct {
 
 // GetEvents gets a list of all events in Home Assistant.
 func (c *Client) GetEvents(ctx context.Context) ([]Event, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "events", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "events", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -262,14 +310,14 @@ type Services struct {
 			Required    bool           `json:"required"`
 			Selector    map[string]any `json:"selector"`
 		} `json:"fields"`
-		Response map[string]any `json:"response"`
-		Target   map[string]any `json:"target"`
+		Response *types.ServiceResponse `json:"response"`
+		Target   map[string]any         `json:"target"`
 	} `json:"services"`
 }
 
 // GetServices gets a list of all services in Home Assistant.
 func (c *Client) GetServices(ctx context.Context) ([]Services, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "services", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "services", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -284,43 +332,39 @@ func (c *Client) GetServices(ctx context.Context) ([]Services, error) {
 
 type (
 	GetHistoryOptions struct {
-		Timestamp              time.Time
-		EndTime                time.Time
-		MinimalResponse        bool
-		NoAttributes           bool
+		Timestamp       time.Time // Defaults to one day ago
+		EndTime         time.Time // Defaults to one day after Timestamp
+		MinimalResponse bool      // Only send attributes for the first state of each entity
+		NoAttributes    bool
+		AllChanges      bool // Include attribute only changes, not just significant changes
+
+		// Deprecated: Only significant changes are returned by default, use
+		// AllChanges to include every change.
 		SignificantChangesOnly bool
 	}
-	History []types.Entity
+
+	// History is the states of each entity, oldest first.
+	History map[entity.ID][]types.Entity
 )
 
 // GetHistory gets the history of events and state changes in Home Assistant.
 func (c *Client) GetHistory(ctx context.Context, entityIDs []string, opts GetHistoryOptions) (History, error) {
 	path := "history/period"
 	if !opts.Timestamp.IsZero() {
-		path += "/" + url.QueryEscape(opts.Timestamp.Format(time.RFC3339))
-	}
-
-	if len(entityIDs) > 0 {
-		path += "?filter_entity_id=" + strings.Join(entityIDs, ",")
-	}
-
-	if !opts.EndTime.IsZero() {
-		path += "?end_time=" + url.QueryEscape(opts.EndTime.Format(time.RFC3339))
-	}
-
-	if opts.MinimalResponse {
-		path += "?minimal_response"
+		path += "/" + opts.Timestamp.Format(time.RFC3339)
 	}
 
-	if opts.NoAttributes {
-		path += "?no_attributes"
-	}
+	q := query{}
+	q.setList("filter_entity_id", entityIDs)
+	q.setTime("end_time", opts.EndTime)
+	q.flag("minimal_response", opts.MinimalResponse)
+	q.flag("no_attributes", opts.NoAttributes)
 
-	if opts.SignificantChangesOnly {
-		path += "?significant_changes_only"
+	if opts.AllChanges {
+		q.set("significant_changes_only", "0")
 	}
 
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, path, q, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -333,6 +377,42 @@ func (c *Client) GetHistory(ctx context.Context, entityIDs []string, opts GetHis
 	return resp, nil
 }
 
+// UnmarshalJSON groups the list of states sent for each entity by entity ID.
+// With a minimal response only the first state of each entity is complete. The
+// rest only have the state and last_changed, so their entity ID is taken from
+// the first state and their last update from their last change. Attributes are
+// left nil as they may have changed in between.
+func (h *History) UnmarshalJSON(data []byte) error {
+	var lists [][]types.Entity
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return err
+	}
+
+	*h = make(History, len(lists))
+
+	for _, states := range lists {
+		if len(states) == 0 {
+			continue
+		}
+
+		entityID := states[0].EntityID
+
+		for i := range states {
+			if states[i].EntityID == (entity.ID{}) {
+				states[i].EntityID = entityID
+			}
+
+			if states[i].LastUpdated.IsZero() {
+				states[i].LastUpdated = states[i].LastChanged
+			}
+		}
+
+		(*h)[entityID] = append((*h)[entityID], states...)
+	}
+
+	return nil
+}
+
 type (
 	LogbookEntry struct {
 		ContextUserID *string        `json:"context_user_id"`
@@ -356,20 +436,15 @@ func (c *Client) GetLogbook(ctx context.Context, opts GetLogbookOptions) ([]Logb
 		path += "/" + opts.Timestamp.Format(time.RFC3339)
 	}
 
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	q := query{}
+	q.setTime("end_time", opts.EndTime)
+	q.set("entity", opts.EntityID)
+
+	req, err := c.newRequest(ctx, http.MethodGet, path, q, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	q := req.URL.Query()
-	if !opts.EndTime.IsZero() {
-		q.Add("end_time", opts.EndTime.Format(time.RFC3339))
-	}
-
-	if opts.EntityID != "" {
-		q.Add("entity", opts.EntityID)
-	}
-
 	var resp []LogbookEntry
 	if err = c.sendRequest(req, &resp); err != nil {
 		return nil, err
@@ -380,7 +455,7 @@ func (c *Client) GetLogbook(ctx context.Context, opts GetLogbookOptions) ([]Logb
 
 // GetStates gets a list of all states in Home Assistant.
 func (c *Client) GetStates(ctx context.Context) ([]types.Entity, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "states", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "states", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -395,7 +470,7 @@ func (c *Client) GetStates(ctx context.Context) ([]types.Entity, error) {
 
 // GetState gets the state of an entity in Home Assistant.
 func (c *Client) GetState(ctx context.Context, entityID string) (types.Entity, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "states/"+entityID, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "states/"+entityID, nil, nil)
 	if err != nil {
 		return types.Entity{}, err
 	}
@@ -410,7 +485,7 @@ func (c *Client) GetState(ctx context.Context, entityID string) (types.Entity, e
 
 // GetErrorLog gets the error log in Home Assistant.
 func (c *Client) GetErrorLog(ctx context.Context) ([]map[string]any, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "error_log", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "error_log", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -425,7 +500,7 @@ func (c *Client) GetErrorLog(ctx context.Context) ([]map[string]any, error) {
 
 // GetCameraProxy gets a proxy URL for a camera in Home Assistant.
 func (c *Client) GetCameraProxy(ctx context.Context, entityID string) (string, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "camera_proxy/"+entityID, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "camera_proxy/"+entityID, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -450,7 +525,7 @@ type Calendars struct {
 
 // GetCalendars gets a list of calendar entities in Home Assistant.
 func (c *Client) GetCalendars(ctx context.Context, calendarID string) ([]Calendars, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, "calendars/"+calendarID, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "calendars/"+calendarID, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -483,16 +558,16 @@ func (c *Client) GetCalendarEvents(
 	start *time.Time,
 	end *time.Time,
 ) (CalendarEvents, error) {
-	path := "calendars/" + calendarID
+	q := query{}
 	if start != nil {
-		path += "?start=" + start.Format(time.RFC3339)
+		q.setTime("start", *start)
 	}
 
 	if end != nil {
-		path += "?end=" + end.Format(time.RFC3339)
+		q.setTime("end", *end)
 	}
 
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "calendars/"+calendarID, q, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -514,7 +589,7 @@ type UpsertStateRequest struct {
 // UpsertState updates or creates a state in Home Assistant.
 // Returns a state object and a URL of the new resource if one is created.
 func (c *Client) UpsertState(ctx context.Context, params UpsertStateRequest) (types.Entity, *url.URL, error) {
-	req, err := c.newRequest(ctx, http.MethodPost, "states/"+params.EntityID, params)
+	req, err := c.newRequest(ctx, http.MethodPost, "states/"+params.EntityID, nil, params)
 	if err != nil {
 		return types.Entity{}, nil, err
 	}
@@ -551,7 +626,7 @@ func (c *Client) UpsertState(ctx context.Context, params UpsertStateRequest) (ty
 // FireEvent fires an event in Home Assistant.
 // Returns a message if successful.
 func (c *Client) FireEvent(ctx context.Context, eventType string, eventData any) (string, error) {
-	req, err := c.newRequest(ctx, http.MethodPost, "events/"+eventType, eventData)
+	req, err := c.newRequest(ctx, http.MethodPost, "events/"+eventType, nil, eventData)
 	if err != nil {
 		return "", err
 	}
@@ -564,10 +639,7 @@ func (c *Client) FireEvent(ctx context.Context, eventType string, eventData any)
 	return resp.Message, nil
 }
 
-type CallServiceParams struct {
-	Domain  domains.Domain
-	Service string
-}
+type CallServiceParams = types.CallServiceParams
 
 // CallService calls a Home Assistant service via the REST API.
 // Returns a list of states that have changed while the service was being executed.
@@ -577,7 +649,7 @@ func (c *Client) CallService(
 	service string,
 	data any,
 ) ([]types.Entity, error) {
-	req, err := c.newRequest(ctx, http.MethodPost, "services/"+domain.String()+"/"+service, data)
+	req, err := c.newRequest(ctx, http.MethodPost, "services/"+domain.String()+"/"+service, nil, data)
 	if err != nil {
 		return nil, err
 	}
@@ -590,6 +662,117 @@ func (c *Client) CallService(
 	return resp, nil
 }
 
+// CallServiceWithParams calls a service described by params, such as one built
+// with the services packages. The REST API takes the target as part of the
+// service data, so the two are merged into one body.
+func (c *Client) CallServiceWithParams(ctx context.Context, params CallServiceParams) ([]types.Entity, error) {
+	data, err := serviceBody(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.CallService(ctx, params.Domain, params.Service, data)
+}
+
+// CallServiceWithResponse calls a service that returns data, such as
+// weather.get_forecasts, and decodes the response into response. Calls to
+// services that don't return a response are rejected before being sent.
+// Returns a list of states that have changed while the service was being executed.
+func (c *Client) CallServiceWithResponse(
+	ctx context.Context,
+	params CallServiceParams,
+	response any,
+) ([]types.Entity, error) {
+	if err := c.checkServiceResponse(ctx, params); err != nil {
+		return nil, err
+	}
+
+	data, err := serviceBody(params)
+	if err != nil {
+		return nil, err
+	}
+
+	q := query{}
+	q.flag("return_response", true)
+
+	req, err := c.newRequest(ctx, http.MethodPost, "services/"+params.Domain.String()+"/"+params.Service, q, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		ChangedStates   []types.Entity  `json:"changed_states"`
+		ServiceResponse json.RawMessage `json:"service_response"`
+	}
+
+	if err = c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+
+	if response != nil && resp.ServiceResponse != nil {
+		if err := json.Unmarshal(resp.ServiceResponse, response); err != nil {
+			return nil, fmt.Errorf("failed to decode service response: %w", err)
+		}
+	}
+
+	return resp.ChangedStates, nil
+}
+
+// Check that a service can return a response. Services are cached and only
+// fetched again when the service isn't known, in case it was added since.
+func (c *Client) checkServiceResponse(ctx context.Context, params CallServiceParams) error {
+	c.mu.Lock()
+	services := c.services
+	c.mu.Unlock()
+
+	err := services.CheckResponse(params.Domain, params.Service)
+	if services != nil && !errors.Is(err, types.ErrServiceNotFound) {
+		return err
+	}
+
+	domainServices, err := c.GetServices(ctx)
+	if err != nil {
+		return err
+	}
+
+	services = make(types.Services, len(domainServices))
+
+	for _, d := range domainServices {
+		services[d.Domain.String()] = make(types.DomainServices, len(d.Services))
+		for name, svc := range d.Services {
+			services[d.Domain.String()][name] = types.Service{Response: svc.Response}
+		}
+	}
+
+	c.mu.Lock()
+	c.services = services
+	c.mu.Unlock()
+
+	return services.CheckResponse(params.Domain, params.Service)
+}
+
+// Merge the service data and target of a service call into a single object.
+func serviceBody(params CallServiceParams) (map[string]any, error) {
+	body := make(map[string]any)
+
+	for _, v := range []any{params.ServiceData, params.Target} {
+		if v == nil {
+			continue
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal service data: %w", err)
+		}
+
+		if err := json.Unmarshal(raw, &body); err != nil {
+			return nil, fmt.Errorf("service data must be an object: %w", err)
+		}
+	}
+
+	return body, nil
+}
+
 type Template struct {
 	Template  string         `json:"template"`
 	Variables map[string]any `json:"variable,omitempty"`
@@ -597,7 +780,7 @@ type Template struct {
 
 // RenderTemplate renders a Home Assistant template.
 func (c *Client) RenderTemplate(ctx context.Context, template Template) (string, error) {
-	req, err := c.newRequest(ctx, http.MethodPost, "template", template)
+	req, err := c.newRequest(ctx, http.MethodPost, "template", nil, template)
 	if err != nil {
 		return "", err
 	}
@@ -630,7 +813,7 @@ type checkConfig struct {
 // If the checks is successful, nil will be returned.
 // If the check fails, a string containing the error will be returned.
 func (c *Client) CheckConfig(ctx context.Context) error {
-	req, err := c.newRequest(ctx, http.MethodPost, "config/core/check_config", nil)
+	req, err := c.newRequest(ctx, http.MethodPost, "config/core/check_config", nil, nil)
 	if err != nil {
 		return err
 	}
@@ -650,7 +833,7 @@ func (c *Client) CheckConfig(ctx context.Context) error {
 // HandleIntent handles an intent in Home Assistant.
 // You must add intent: to your Home Assistant configuration file to enable this endpoint.
 func (c *Client) HandleIntent(ctx context.Context, intent any) error {
-	req, err := c.newRequest(ctx, http.MethodPost, "services/intent/handle", intent)
+	req, err := c.newRequest(ctx, http.MethodPost, "services/intent/handle", nil, intent)
 	if err != nil {
 		return err
 	}
@@ -671,12 +854,10 @@ func (c *Client) EventStream(
 	stop <-chan struct{},
 	restrictions ...string,
 ) error {
-	path := "stream"
-	if len(restrictions) > 0 {
-		path += "?restrict=" + strings.Join(restrictions, ",")
-	}
+	q := query{}
+	q.setList("restrict", restrictions)
 
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, "stream", q, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -157,6 +158,33 @@ func TestClient(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, states, 2)
 		assert.Equal(t, entity1, states[0].EntityID)
-		assert.Equal(t, "on", states[0].State)
+		assert.Equal(t, state.Value("on"), states[0].State)
+	})
+
+	t.Run("CallServiceWithParams", func(t *testing.T) {
+		kitchen, err := entity.Parse("light.kitchen")
+		assert.NoError(t, err)
+
+		var (
+			path string
+			body map[string]any
+		)
+
+		testServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[]`))
+		})
+
+		_, err = client.CallServiceWithParams(ctx, CallServiceParams{
+			Domain:      "light",
+			Service:     "turn_on",
+			ServiceData: map[string]any{"brightness": 200},
+			Target:      types.ServiceTarget{EntityID: entity.IDList{kitchen}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "/api/services/light/turn_on", path)
+		assert.Equal(t, map[string]any{"brightness": float64(200), "entity_id": []any{"light.kitchen"}}, body)
 	})
 }
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/hatest"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallServiceWithResponse(t *testing.T) {
+	server := hatest.NewServer(t, hatest.WithServices(types.Services{
+		"todo":  {"get_items": {Response: &types.ServiceResponse{}}},
+		"light": {"turn_on": {}},
+	}))
+	server.HandleService("todo", "get_items", func(hatest.ServiceCall) (any, error) {
+		return map[string]any{"todo.shopping": map[string]any{"items": []any{map[string]any{"summary": "Milk"}}}}, nil
+	})
+
+	var servicesFetched atomic.Int32
+
+	client, err := NewClient(server.URL, server.Token, WithCustomHTTPClient(&http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/api/services" {
+				servicesFetched.Add(1)
+			}
+
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	var items map[string]struct {
+		Items []struct {
+			Summary string `json:"summary"`
+		} `json:"items"`
+	}
+
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "todo", Service: "get_items"}, &items)
+	require.NoError(t, err)
+	assert.Equal(t, "Milk", items["todo.shopping"].Items[0].Summary)
+
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "light", Service: "turn_on"}, nil)
+	assert.ErrorIs(t, err, types.ErrServiceNoResponse)
+
+	// Known services are checked against the cache
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "todo", Service: "get_items"}, &items)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), servicesFetched.Load())
+
+	// Unknown ones fetch the services again
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "todo", Service: "add_item"}, nil)
+	assert.ErrorIs(t, err, types.ErrServiceNotFound)
+	assert.Equal(t, int32(2), servicesFetched.Load())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetHistory(t *testing.T) {
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", map[string]any{"brightness": nil})
+	server.SetState("light.kitchen", "on", map[string]any{"brightness": 200})
+	server.SetState("light.hallway", "on", nil)
+
+	client, err := NewClient(server.URL, server.Token)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	kitchen := entity.MustParse("light.kitchen")
+
+	history, err := client.GetHistory(ctx, []string{"light.kitchen"}, GetHistoryOptions{
+		Timestamp:       time.Now().Add(-time.Minute),
+		EndTime:         time.Now().Add(time.Minute),
+		MinimalResponse: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Len(t, history[kitchen], 2)
+
+	latest := history[kitchen][1]
+	assert.Equal(t, kitchen, latest.EntityID)
+	assert.Equal(t, state.Value("on"), latest.State)
+	assert.Nil(t, latest.Attributes)
+	assert.Equal(t, latest.LastChanged, latest.LastUpdated)
+	assert.NotNil(t, history[kitchen][0].Attributes)
+
+	logbook, err := client.GetLogbook(ctx, GetLogbookOptions{EndTime: time.Now().Add(time.Minute), EntityID: "light.hallway"})
+	require.NoError(t, err)
+	require.Len(t, logbook, 1)
+	assert.Equal(t, entity.MustParse("light.hallway"), logbook[0].EntityID)
+}
+
+func TestQueryEncode(t *testing.T) {
+	q := query{}
+	q.setList("filter_entity_id", []string{"light.kitchen", "light.hallway"})
+	q.setTime("end_time", time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("", 3600)))
+	q.set("entity", "")
+	q.flag("minimal_response", true)
+	q.flag("no_attributes", false)
+
+	assert.Equal(t, "end_time=2024-01-01T12%3A00%3A00%2B01%3A00&filter_entity_id=light.kitchen%2Clight.hallway&minimal_response", q.encode())
+}
@@ -0,0 +1,80 @@
+// Service calls for the climate domain.
+// https://www.home-assistant.io/integrations/climate/#actions
+
+package climate
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type SetTemperatureCall struct {
+	*services.Call
+}
+
+// SetTemperature sets the target temperature of climate entities. Use
+// Temperature for a single target or Range for heat_cool mode.
+func SetTemperature(target types.ServiceTarget) *SetTemperatureCall {
+	return &SetTemperatureCall{services.New(domains.Climate, "set_temperature", target)}
+}
+
+// Temperature sets a single target temperature.
+func (c *SetTemperatureCall) Temperature(temperature float64) *SetTemperatureCall {
+	c.Set("temperature", temperature)
+	return c
+}
+
+// Range sets the low and high target temperatures.
+func (c *SetTemperatureCall) Range(low, high float64) *SetTemperatureCall {
+	c.Set("target_temp_low", low)
+	c.Set("target_temp_high", high)
+
+	return c
+}
+
+// HVACMode changes the HVAC mode at the same time.
+func (c *SetTemperatureCall) HVACMode(mode types.HVACMode) *SetTemperatureCall {
+	c.Set("hvac_mode", mode)
+	return c
+}
+
+// SetHVACMode sets the HVAC mode of climate entities.
+func SetHVACMode(target types.ServiceTarget, mode types.HVACMode) *services.Call {
+	return services.New(domains.Climate, "set_hvac_mode", target).Set("hvac_mode", mode)
+}
+
+// SetFanMode sets the fan mode of climate entities.
+func SetFanMode(target types.ServiceTarget, mode string) *services.Call {
+	return services.New(domains.Climate, "set_fan_mode", target).Set("fan_mode", mode)
+}
+
+// SetPresetMode sets the preset mode of climate entities.
+func SetPresetMode(target types.ServiceTarget, mode string) *services.Call {
+	return services.New(domains.Climate, "set_preset_mode", target).Set("preset_mode", mode)
+}
+
+// SetSwingMode sets the swing mode of climate entities.
+func SetSwingMode(target types.ServiceTarget, mode string) *services.Call {
+	return services.New(domains.Climate, "set_swing_mode", target).Set("swing_mode", mode)
+}
+
+// SetHumidity sets the target humidity of climate entities.
+func SetHumidity(target types.ServiceTarget, humidity int) *services.Call {
+	return services.New(domains.Climate, "set_humidity", target).Set("humidity", humidity)
+}
+
+// TurnOn turns on climate entities.
+func TurnOn(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Climate, "turn_on", target)
+}
+
+// TurnOff turns off climate entities.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Climate, "turn_off", target)
+}
+
+// Toggle turns climate entities on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Climate, "toggle", target)
+}
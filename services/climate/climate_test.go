@@ -0,0 +1,36 @@
+package climate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTemperature(t *testing.T) {
+	thermostat, err := entity.Parse("climate.living_room")
+	require.NoError(t, err)
+
+	params := SetTemperature(services.Entities(thermostat)).
+		Range(19.5, 23).
+		HVACMode(types.HVACModeHeatCool).
+		Params()
+
+	assert.Equal(t, domains.Climate, params.Domain)
+	assert.Equal(t, "set_temperature", params.Service)
+	assert.Equal(t, entity.IDList{thermostat}, params.Target.EntityID)
+
+	data, err := json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"target_temp_low": 19.5, "target_temp_high": 23, "hvac_mode": "heat_cool"}`, string(data))
+
+	params = SetHVACMode(services.Areas("upstairs"), types.HVACModeOff).Params()
+	assert.Equal(t, "set_hvac_mode", params.Service)
+	assert.Equal(t, []string{"upstairs"}, params.Target.AreaID)
+	assert.Equal(t, map[string]any{"hvac_mode": types.HVACModeOff}, params.ServiceData)
+}
@@ -0,0 +1,60 @@
+// Service calls for the cover domain.
+// https://www.home-assistant.io/integrations/cover/#actions
+
+package cover
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Open opens covers.
+func Open(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "open_cover", target)
+}
+
+// Close closes covers.
+func Close(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "close_cover", target)
+}
+
+// Stop stops covers that are moving.
+func Stop(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "stop_cover", target)
+}
+
+// Toggle opens covers that are closed and closes those that are open.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "toggle", target)
+}
+
+// SetPosition moves covers to a position, from 0 for closed to 100 for open.
+func SetPosition(target types.ServiceTarget, position int) *services.Call {
+	return services.New(domains.Cover, "set_cover_position", target).Set("position", position)
+}
+
+// OpenTilt tilts covers open.
+func OpenTilt(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "open_cover_tilt", target)
+}
+
+// CloseTilt tilts covers closed.
+func CloseTilt(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "close_cover_tilt", target)
+}
+
+// StopTilt stops covers that are tilting.
+func StopTilt(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "stop_cover_tilt", target)
+}
+
+// ToggleTilt tilts covers open if they are closed and closed if they are open.
+func ToggleTilt(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Cover, "toggle_cover_tilt", target)
+}
+
+// SetTiltPosition tilts covers to a position, from 0 for closed to 100 for open.
+func SetTiltPosition(target types.ServiceTarget, position int) *services.Call {
+	return services.New(domains.Cover, "set_cover_tilt_position", target).Set("tilt_position", position)
+}
@@ -0,0 +1,31 @@
+package cover
+
+import (
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPosition(t *testing.T) {
+	blinds, err := entity.Parse("cover.office_blinds")
+	require.NoError(t, err)
+
+	params := SetPosition(services.Entities(blinds), 40).Params()
+	assert.Equal(t, domains.Cover, params.Domain)
+	assert.Equal(t, "set_cover_position", params.Service)
+	assert.Equal(t, entity.IDList{blinds}, params.Target.EntityID)
+	assert.Equal(t, map[string]any{"position": 40}, params.ServiceData)
+
+	params = SetTiltPosition(services.Entities(blinds), 75).Params()
+	assert.Equal(t, "set_cover_tilt_position", params.Service)
+	assert.Equal(t, map[string]any{"tilt_position": 75}, params.ServiceData)
+
+	params = Open(services.Floors("ground")).Params()
+	assert.Equal(t, "open_cover", params.Service)
+	assert.Equal(t, []string{"ground"}, params.Target.FloorID)
+	assert.Nil(t, params.ServiceData)
+}
@@ -0,0 +1,91 @@
+// Service calls for the fan domain.
+// https://www.home-assistant.io/integrations/fan/#actions
+
+package fan
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	TurnOnCall struct {
+		*services.Call
+	}
+
+	Direction string
+)
+
+const (
+	DirectionForward Direction = "forward"
+	DirectionReverse Direction = "reverse"
+)
+
+// TurnOn turns on fans.
+func TurnOn(target types.ServiceTarget) *TurnOnCall {
+	return &TurnOnCall{services.New(domains.Fan, "turn_on", target)}
+}
+
+// Percentage sets the speed as a percentage.
+func (c *TurnOnCall) Percentage(pct int) *TurnOnCall {
+	c.Set("percentage", pct)
+	return c
+}
+
+// PresetMode sets the preset mode.
+func (c *TurnOnCall) PresetMode(mode string) *TurnOnCall {
+	c.Set("preset_mode", mode)
+	return c
+}
+
+// TurnOff turns off fans.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Fan, "turn_off", target)
+}
+
+// Toggle turns fans on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Fan, "toggle", target)
+}
+
+// SetPercentage sets the speed of fans as a percentage.
+func SetPercentage(target types.ServiceTarget, pct int) *services.Call {
+	return services.New(domains.Fan, "set_percentage", target).Set("percentage", pct)
+}
+
+// IncreaseSpeed increases the speed of fans by step percent, or by one speed
+// step if step is zero.
+func IncreaseSpeed(target types.ServiceTarget, step int) *services.Call {
+	return speedStep("increase_speed", target, step)
+}
+
+// DecreaseSpeed decreases the speed of fans by step percent, or by one speed
+// step if step is zero.
+func DecreaseSpeed(target types.ServiceTarget, step int) *services.Call {
+	return speedStep("decrease_speed", target, step)
+}
+
+func speedStep(service string, target types.ServiceTarget, step int) *services.Call {
+	call := services.New(domains.Fan, service, target)
+	if step != 0 {
+		call.Set("percentage_step", step)
+	}
+
+	return call
+}
+
+// SetPresetMode sets the preset mode of fans.
+func SetPresetMode(target types.ServiceTarget, mode string) *services.Call {
+	return services.New(domains.Fan, "set_preset_mode", target).Set("preset_mode", mode)
+}
+
+// Oscillate turns oscillation of fans on or off.
+func Oscillate(target types.ServiceTarget, oscillating bool) *services.Call {
+	return services.New(domains.Fan, "oscillate", target).Set("oscillating", oscillating)
+}
+
+// SetDirection sets the direction fans rotate.
+func SetDirection(target types.ServiceTarget, direction Direction) *services.Call {
+	return services.New(domains.Fan, "set_direction", target).Set("direction", direction)
+}
@@ -0,0 +1,42 @@
+package fan
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnOn(t *testing.T) {
+	ceiling, err := entity.Parse("fan.bedroom_ceiling")
+	require.NoError(t, err)
+
+	params := TurnOn(services.Entities(ceiling)).Percentage(66).PresetMode("sleep").Params()
+	assert.Equal(t, domains.Fan, params.Domain)
+	assert.Equal(t, "turn_on", params.Service)
+	assert.Equal(t, entity.IDList{ceiling}, params.Target.EntityID)
+	assert.Equal(t, map[string]any{"percentage": 66, "preset_mode": "sleep"}, params.ServiceData)
+}
+
+func TestSpeedStep(t *testing.T) {
+	ceiling, err := entity.Parse("fan.bedroom_ceiling")
+	require.NoError(t, err)
+
+	params := IncreaseSpeed(services.Entities(ceiling), 10).Params()
+	assert.Equal(t, "increase_speed", params.Service)
+	assert.Equal(t, map[string]any{"percentage_step": 10}, params.ServiceData)
+
+	// Without a step Home Assistant uses the fan's own speed step
+	params = DecreaseSpeed(services.Entities(ceiling), 0).Params()
+	assert.Equal(t, "decrease_speed", params.Service)
+	assert.Nil(t, params.ServiceData)
+
+	params = SetDirection(services.Entities(ceiling), DirectionReverse).Params()
+	data, err := json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"direction": "reverse"}`, string(data))
+}
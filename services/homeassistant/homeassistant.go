@@ -0,0 +1,40 @@
+// Service calls for the homeassistant domain, which work across domains.
+// https://www.home-assistant.io/integrations/homeassistant/#actions
+
+package homeassistant
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// TurnOn turns on entities of any domain that supports it.
+func TurnOn(target types.ServiceTarget) *services.Call {
+	return services.New(domains.HomeAssistant, "turn_on", target)
+}
+
+// TurnOff turns off entities of any domain that supports it.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.HomeAssistant, "turn_off", target)
+}
+
+// Toggle toggles entities of any domain that supports it.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.HomeAssistant, "toggle", target)
+}
+
+// UpdateEntity asks the integrations behind entities to refresh them.
+func UpdateEntity(target types.ServiceTarget) *services.Call {
+	return services.New(domains.HomeAssistant, "update_entity", target)
+}
+
+// ReloadCoreConfig reloads the core configuration.
+func ReloadCoreConfig() *services.Call {
+	return services.New(domains.HomeAssistant, "reload_core_config", types.ServiceTarget{})
+}
+
+// Restart restarts Home Assistant.
+func Restart() *services.Call {
+	return services.New(domains.HomeAssistant, "restart", types.ServiceTarget{})
+}
@@ -0,0 +1,25 @@
+// Service calls for the input_boolean domain.
+// https://www.home-assistant.io/integrations/input_boolean/#actions
+
+package inputboolean
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// TurnOn turns on input booleans.
+func TurnOn(target types.ServiceTarget) *services.Call {
+	return services.New(domains.InputBoolean, "turn_on", target)
+}
+
+// TurnOff turns off input booleans.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.InputBoolean, "turn_off", target)
+}
+
+// Toggle turns input booleans on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.InputBoolean, "toggle", target)
+}
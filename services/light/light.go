@@ -0,0 +1,169 @@
+// Service calls for the light domain.
+// https://www.home-assistant.io/integrations/light/#actions
+
+package light
+
+import (
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	TurnOnCall struct {
+		*services.Call
+	}
+
+	TurnOffCall struct {
+		*services.Call
+	}
+
+	ToggleCall struct {
+		*services.Call
+	}
+
+	Flash string
+)
+
+const (
+	FlashShort Flash = "short"
+	FlashLong  Flash = "long"
+)
+
+// TurnOn turns on lights, optionally changing their brightness, color or effect.
+func TurnOn(target types.ServiceTarget) *TurnOnCall {
+	return &TurnOnCall{services.New(domains.Light, "turn_on", target)}
+}
+
+// Transition sets how long the change takes.
+func (c *TurnOnCall) Transition(d time.Duration) *TurnOnCall {
+	c.Set("transition", d.Seconds())
+	return c
+}
+
+// Brightness sets the brightness from 0 to 255.
+func (c *TurnOnCall) Brightness(brightness int) *TurnOnCall {
+	c.Set("brightness", brightness)
+	return c
+}
+
+// BrightnessPct sets the brightness as a percentage.
+func (c *TurnOnCall) BrightnessPct(pct int) *TurnOnCall {
+	c.Set("brightness_pct", pct)
+	return c
+}
+
+// BrightnessStep changes the brightness by step, from -255 to 255.
+func (c *TurnOnCall) BrightnessStep(step int) *TurnOnCall {
+	c.Set("brightness_step", step)
+	return c
+}
+
+// BrightnessStepPct changes the brightness by a percentage, from -100 to 100.
+func (c *TurnOnCall) BrightnessStepPct(pct int) *TurnOnCall {
+	c.Set("brightness_step_pct", pct)
+	return c
+}
+
+// ColorTempKelvin sets the color temperature.
+func (c *TurnOnCall) ColorTempKelvin(kelvin int) *TurnOnCall {
+	c.Set("color_temp_kelvin", kelvin)
+	return c
+}
+
+// RGBColor sets the color from red, green and blue values from 0 to 255.
+func (c *TurnOnCall) RGBColor(r, g, b int) *TurnOnCall {
+	c.Set("rgb_color", []int{r, g, b})
+	return c
+}
+
+// RGBWColor sets the color from red, green, blue and white values from 0 to 255.
+func (c *TurnOnCall) RGBWColor(r, g, b, w int) *TurnOnCall {
+	c.Set("rgbw_color", []int{r, g, b, w})
+	return c
+}
+
+// RGBWWColor sets the color from red, green, blue, cold white and warm white
+// values from 0 to 255.
+func (c *TurnOnCall) RGBWWColor(r, g, b, cw, ww int) *TurnOnCall {
+	c.Set("rgbww_color", []int{r, g, b, cw, ww})
+	return c
+}
+
+// HSColor sets the color from a hue from 0 to 360 and a saturation from 0 to 100.
+func (c *TurnOnCall) HSColor(hue, saturation float64) *TurnOnCall {
+	c.Set("hs_color", []float64{hue, saturation})
+	return c
+}
+
+// XYColor sets the color from CIE xy coordinates.
+func (c *TurnOnCall) XYColor(x, y float64) *TurnOnCall {
+	c.Set("xy_color", []float64{x, y})
+	return c
+}
+
+// ColorName sets the color from a CSS color name.
+func (c *TurnOnCall) ColorName(name string) *TurnOnCall {
+	c.Set("color_name", name)
+	return c
+}
+
+// White switches the light to white mode at the given brightness.
+func (c *TurnOnCall) White(brightness int) *TurnOnCall {
+	c.Set("white", brightness)
+	return c
+}
+
+// Effect sets the light effect.
+func (c *TurnOnCall) Effect(effect string) *TurnOnCall {
+	c.Set("effect", effect)
+	return c
+}
+
+// Flash makes the light flash.
+func (c *TurnOnCall) Flash(flash Flash) *TurnOnCall {
+	c.Set("flash", flash)
+	return c
+}
+
+// Profile applies a light profile.
+func (c *TurnOnCall) Profile(profile string) *TurnOnCall {
+	c.Set("profile", profile)
+	return c
+}
+
+// TurnOff turns off lights.
+func TurnOff(target types.ServiceTarget) *TurnOffCall {
+	return &TurnOffCall{services.New(domains.Light, "turn_off", target)}
+}
+
+// Transition sets how long the change takes.
+func (c *TurnOffCall) Transition(d time.Duration) *TurnOffCall {
+	c.Set("transition", d.Seconds())
+	return c
+}
+
+// Flash makes the light flash.
+func (c *TurnOffCall) Flash(flash Flash) *TurnOffCall {
+	c.Set("flash", flash)
+	return c
+}
+
+// Toggle turns lights on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *ToggleCall {
+	return &ToggleCall{services.New(domains.Light, "toggle", target)}
+}
+
+// Transition sets how long the change takes.
+func (c *ToggleCall) Transition(d time.Duration) *ToggleCall {
+	c.Set("transition", d.Seconds())
+	return c
+}
+
+// Brightness sets the brightness from 0 to 255 when turning on.
+func (c *ToggleCall) Brightness(brightness int) *ToggleCall {
+	c.Set("brightness", brightness)
+	return c
+}
@@ -0,0 +1,37 @@
+package light
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnOn(t *testing.T) {
+	kitchen, err := entity.Parse("light.kitchen")
+	require.NoError(t, err)
+
+	params := TurnOn(services.Entities(kitchen)).
+		Brightness(200).
+		Transition(2*time.Second).
+		RGBColor(255, 0, 0).
+		Params()
+
+	assert.Equal(t, domains.Light, params.Domain)
+	assert.Equal(t, "turn_on", params.Service)
+	assert.Equal(t, entity.IDList{kitchen}, params.Target.EntityID)
+
+	data, err := json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"brightness": 200, "transition": 2, "rgb_color": [255, 0, 0]}`, string(data))
+
+	params = TurnOff(services.Areas("kitchen")).Params()
+	assert.Equal(t, "turn_off", params.Service)
+	assert.Equal(t, []string{"kitchen"}, params.Target.AreaID)
+	assert.Nil(t, params.ServiceData)
+}
@@ -0,0 +1,35 @@
+// Service calls for the lock domain.
+// https://www.home-assistant.io/integrations/lock/#actions
+
+package lock
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type Call struct {
+	*services.Call
+}
+
+// Lock locks locks.
+func Lock(target types.ServiceTarget) *Call {
+	return &Call{services.New(domains.Lock, "lock", target)}
+}
+
+// Unlock unlocks locks.
+func Unlock(target types.ServiceTarget) *Call {
+	return &Call{services.New(domains.Lock, "unlock", target)}
+}
+
+// Open opens the latch of locks that support it.
+func Open(target types.ServiceTarget) *Call {
+	return &Call{services.New(domains.Lock, "open", target)}
+}
+
+// Code sets the code required by the lock.
+func (c *Call) Code(code string) *Call {
+	c.Set("code", code)
+	return c
+}
@@ -0,0 +1,27 @@
+package lock
+
+import (
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlock(t *testing.T) {
+	door, err := entity.Parse("lock.front_door")
+	require.NoError(t, err)
+
+	params := Unlock(services.Entities(door)).Code("1234").Params()
+	assert.Equal(t, domains.Lock, params.Domain)
+	assert.Equal(t, "unlock", params.Service)
+	assert.Equal(t, entity.IDList{door}, params.Target.EntityID)
+	assert.Equal(t, map[string]any{"code": "1234"}, params.ServiceData)
+
+	params = Lock(services.Labels("exterior")).Params()
+	assert.Equal(t, "lock", params.Service)
+	assert.Equal(t, []string{"exterior"}, params.Target.LabelID)
+	assert.Nil(t, params.ServiceData)
+}
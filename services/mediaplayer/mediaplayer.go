@@ -0,0 +1,162 @@
+// Service calls for the media_player domain.
+// https://www.home-assistant.io/integrations/media_player/#actions
+
+package mediaplayer
+
+import (
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	PlayMediaCall struct {
+		*services.Call
+	}
+
+	Enqueue string
+
+	Repeat string
+)
+
+const (
+	EnqueuePlay    Enqueue = "play"
+	EnqueueNext    Enqueue = "next"
+	EnqueueAdd     Enqueue = "add"
+	EnqueueReplace Enqueue = "replace"
+)
+
+const (
+	RepeatOff Repeat = "off"
+	RepeatAll Repeat = "all"
+	RepeatOne Repeat = "one"
+)
+
+// TurnOn turns on media players.
+func TurnOn(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "turn_on", target)
+}
+
+// TurnOff turns off media players.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "turn_off", target)
+}
+
+// Toggle turns media players on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "toggle", target)
+}
+
+// PlayMedia starts playing content on media players.
+func PlayMedia(target types.ServiceTarget, contentID, contentType string) *PlayMediaCall {
+	call := services.New(domains.MediaPlayer, "play_media", target).
+		Set("media_content_id", contentID).
+		Set("media_content_type", contentType)
+
+	return &PlayMediaCall{call}
+}
+
+// Enqueue adds the content to the queue instead of playing it immediately.
+func (c *PlayMediaCall) Enqueue(enqueue Enqueue) *PlayMediaCall {
+	c.Set("enqueue", enqueue)
+	return c
+}
+
+// Announce plays the content over whatever is playing and then resumes it.
+func (c *PlayMediaCall) Announce() *PlayMediaCall {
+	c.Set("announce", true)
+	return c
+}
+
+// Play resumes playback.
+func Play(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_play", target)
+}
+
+// Pause pauses playback.
+func Pause(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_pause", target)
+}
+
+// PlayPause toggles between playing and paused.
+func PlayPause(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_play_pause", target)
+}
+
+// Stop stops playback.
+func Stop(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_stop", target)
+}
+
+// NextTrack skips to the next track.
+func NextTrack(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_next_track", target)
+}
+
+// PreviousTrack goes back to the previous track.
+func PreviousTrack(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "media_previous_track", target)
+}
+
+// Seek moves playback to a position.
+func Seek(target types.ServiceTarget, position time.Duration) *services.Call {
+	return services.New(domains.MediaPlayer, "media_seek", target).Set("seek_position", position.Seconds())
+}
+
+// VolumeSet sets the volume, from 0 to 1.
+func VolumeSet(target types.ServiceTarget, level float64) *services.Call {
+	return services.New(domains.MediaPlayer, "volume_set", target).Set("volume_level", level)
+}
+
+// VolumeUp turns the volume up one step.
+func VolumeUp(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "volume_up", target)
+}
+
+// VolumeDown turns the volume down one step.
+func VolumeDown(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "volume_down", target)
+}
+
+// VolumeMute mutes or unmutes media players.
+func VolumeMute(target types.ServiceTarget, muted bool) *services.Call {
+	return services.New(domains.MediaPlayer, "volume_mute", target).Set("is_volume_muted", muted)
+}
+
+// SelectSource switches media players to an input source.
+func SelectSource(target types.ServiceTarget, source string) *services.Call {
+	return services.New(domains.MediaPlayer, "select_source", target).Set("source", source)
+}
+
+// SelectSoundMode switches media players to a sound mode.
+func SelectSoundMode(target types.ServiceTarget, mode string) *services.Call {
+	return services.New(domains.MediaPlayer, "select_sound_mode", target).Set("sound_mode", mode)
+}
+
+// ShuffleSet turns shuffle on or off.
+func ShuffleSet(target types.ServiceTarget, shuffle bool) *services.Call {
+	return services.New(domains.MediaPlayer, "shuffle_set", target).Set("shuffle", shuffle)
+}
+
+// RepeatSet sets the repeat mode.
+func RepeatSet(target types.ServiceTarget, repeat Repeat) *services.Call {
+	return services.New(domains.MediaPlayer, "repeat_set", target).Set("repeat", repeat)
+}
+
+// ClearPlaylist removes everything from the playlist.
+func ClearPlaylist(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "clear_playlist", target)
+}
+
+// Join groups members with the target media player for synchronous playback.
+func Join(target types.ServiceTarget, members ...entity.ID) *services.Call {
+	return services.New(domains.MediaPlayer, "join", target).Set("group_members", entity.IDList(members))
+}
+
+// Unjoin removes media players from their group.
+func Unjoin(target types.ServiceTarget) *services.Call {
+	return services.New(domains.MediaPlayer, "unjoin", target)
+}
@@ -0,0 +1,60 @@
+package mediaplayer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlayMedia(t *testing.T) {
+	speaker, err := entity.Parse("media_player.kitchen")
+	require.NoError(t, err)
+
+	params := PlayMedia(services.Entities(speaker), "https://example.com/song.mp3", "music").
+		Enqueue(EnqueueNext).
+		Announce().
+		Params()
+
+	assert.Equal(t, domains.MediaPlayer, params.Domain)
+	assert.Equal(t, "play_media", params.Service)
+	assert.Equal(t, entity.IDList{speaker}, params.Target.EntityID)
+
+	data, err := json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"media_content_id": "https://example.com/song.mp3",
+		"media_content_type": "music",
+		"enqueue": "next",
+		"announce": true
+	}`, string(data))
+}
+
+func TestSeek(t *testing.T) {
+	speaker, err := entity.Parse("media_player.kitchen")
+	require.NoError(t, err)
+
+	params := Seek(services.Entities(speaker), 90*time.Second+500*time.Millisecond).Params()
+	assert.Equal(t, "media_seek", params.Service)
+	assert.Equal(t, map[string]any{"seek_position": 90.5}, params.ServiceData)
+
+	params = RepeatSet(services.Entities(speaker), RepeatOne).Params()
+	assert.Equal(t, "repeat_set", params.Service)
+
+	data, err := json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"repeat": "one"}`, string(data))
+
+	bedroom, err := entity.Parse("media_player.bedroom")
+	require.NoError(t, err)
+
+	params = Join(services.Entities(speaker), bedroom).Params()
+	data, err = json.Marshal(params.ServiceData)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"group_members": ["media_player.bedroom"]}`, string(data))
+}
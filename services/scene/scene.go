@@ -0,0 +1,27 @@
+// Service calls for the scene domain.
+// https://www.home-assistant.io/integrations/scene/#actions
+
+package scene
+
+import (
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type TurnOnCall struct {
+	*services.Call
+}
+
+// TurnOn activates scenes.
+func TurnOn(target types.ServiceTarget) *TurnOnCall {
+	return &TurnOnCall{services.New(domains.Scene, "turn_on", target)}
+}
+
+// Transition sets how long the change takes, for entities that support it.
+func (c *TurnOnCall) Transition(d time.Duration) *TurnOnCall {
+	c.Set("transition", d.Seconds())
+	return c
+}
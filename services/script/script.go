@@ -0,0 +1,42 @@
+// Service calls for the script domain.
+// https://www.home-assistant.io/integrations/script/#actions
+
+package script
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type TurnOnCall struct {
+	*services.Call
+}
+
+// TurnOn starts scripts without waiting for them to finish.
+func TurnOn(target types.ServiceTarget) *TurnOnCall {
+	return &TurnOnCall{services.New(domains.Script, "turn_on", target)}
+}
+
+// Variables passes variables to the scripts.
+func (c *TurnOnCall) Variables(variables map[string]any) *TurnOnCall {
+	c.Set("variables", variables)
+	return c
+}
+
+// TurnOff stops running scripts.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Script, "turn_off", target)
+}
+
+// Toggle starts scripts that aren't running and stops those that are.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Script, "toggle", target)
+}
+
+// Run calls a script directly, which waits for it to finish. Set the
+// script's fields with Call.Set.
+func Run(id entity.ID) *services.Call {
+	return services.New(domains.Script, id.Name(), types.ServiceTarget{})
+}
@@ -0,0 +1,89 @@
+// Typed builders for Home Assistant service calls. Each domain has its own
+// package, for example services/light, whose builders produce
+// types.CallServiceParams for the REST and websocket clients:
+//
+//	params := light.TurnOn(services.Entities(kitchen)).Brightness(200).Params()
+//	client.CallService(params)
+
+package services
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Call is a service call being built. Domain packages embed it in builders
+// with typed setters for the fields each service accepts.
+type Call struct {
+	domain  domains.Domain
+	service string
+	target  types.ServiceTarget
+	data    map[string]any
+}
+
+// New starts a call to domain.service on target.
+func New(domain domains.Domain, service string, target types.ServiceTarget) *Call {
+	return &Call{
+		domain:  domain,
+		service: service,
+		target:  target,
+		data:    make(map[string]any),
+	}
+}
+
+// Set sets a service data field. Use it for fields without a typed setter.
+// It returns the untyped Call, which ends a chain of typed setters, so call it
+// last or on its own:
+//
+//	call := light.TurnOn(target).Brightness(200)
+//	call.Set("effect", "colorloop")
+func (c *Call) Set(key string, value any) *Call {
+	c.data[key] = value
+	return c
+}
+
+// Params returns the call for use with CallService.
+func (c *Call) Params() types.CallServiceParams {
+	params := types.CallServiceParams{
+		Domain:  c.domain,
+		Service: c.service,
+		Target:  c.target,
+	}
+
+	if len(c.data) > 0 {
+		data := make(map[string]any, len(c.data))
+		for k, v := range c.data {
+			data[k] = v
+		}
+
+		params.ServiceData = data
+	}
+
+	return params
+}
+
+// Entities targets entities.
+func Entities(ids ...entity.ID) types.ServiceTarget {
+	return types.ServiceTarget{EntityID: ids}
+}
+
+// Devices targets every entity of the devices.
+func Devices(ids ...string) types.ServiceTarget {
+	return types.ServiceTarget{DeviceID: ids}
+}
+
+// Areas targets every entity in the areas.
+func Areas(ids ...string) types.ServiceTarget {
+	return types.ServiceTarget{AreaID: ids}
+}
+
+// Floors targets every entity on the floors.
+func Floors(ids ...string) types.ServiceTarget {
+	return types.ServiceTarget{FloorID: ids}
+}
+
+// Labels targets every entity with the labels.
+func Labels(ids ...string) types.ServiceTarget {
+	return types.ServiceTarget{LabelID: ids}
+}
@@ -0,0 +1,26 @@
+// Service calls for the switch domain. The package isn't named switch as that
+// is a Go keyword.
+// https://www.home-assistant.io/integrations/switch/#actions
+
+package switches
+
+import (
+	"github.com/ryanjohnsontv/go-homeassistant/services"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// TurnOn turns on switches.
+func TurnOn(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Switch, "turn_on", target)
+}
+
+// TurnOff turns off switches.
+func TurnOff(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Switch, "turn_off", target)
+}
+
+// Toggle turns switches on if they are off and off if they are on.
+func Toggle(target types.ServiceTarget) *services.Call {
+	return services.New(domains.Switch, "toggle", target)
+}
@@ -22,6 +22,7 @@ const (
 	Fan               Domain = "fan"                 // https://www.home-assistant.io/integrations/fan
 	Geolocation       Domain = "geo_location"        // https://www.home-assistant.io/integrations/geo_location
 	Group             Domain = "group"               // https://www.home-assistant.io/integrations/group
+	HomeAssistant     Domain = "homeassistant"       // https://www.home-assistant.io/integrations/homeassistant
 	Humidifier        Domain = "humidifier"          // https://www.home-assistant.io/integrations/humidifier
 	Image             Domain = "image"               // https://www.home-assistant.io/integrations/image
 	ImageProcessing   Domain = "image_processing"    // https://www.home-assistant.io/integrations/image_processing
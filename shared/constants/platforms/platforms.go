@@ -0,0 +1,44 @@
+package platforms
+
+type Platform string
+
+const (
+	// https://www.home-assistant.io/docs/automation/trigger/#calendar-trigger
+	Calendar Platform = "calendar"
+	// https://www.home-assistant.io/docs/automation/trigger/#device-triggers
+	Device Platform = "device"
+	// https://www.home-assistant.io/docs/automation/trigger/#event-trigger
+	Event Platform = "event"
+	// https://www.home-assistant.io/docs/automation/trigger/#geolocation-trigger
+	GeoLocation Platform = "geo_location"
+	// https://www.home-assistant.io/docs/automation/trigger/#home-assistant-trigger
+	HomeAssistant Platform = "homeassistant"
+	// https://www.home-assistant.io/docs/automation/trigger/#mqtt-trigger
+	MQTT Platform = "mqtt"
+	// https://www.home-assistant.io/docs/automation/trigger/#numeric-state-trigger
+	NumericState Platform = "numeric_state"
+	// https://www.home-assistant.io/docs/automation/trigger/#persistent-notification-trigger
+	PersistentNotification Platform = "persistent_notification"
+	// https://www.home-assistant.io/docs/automation/trigger/#sentence-trigger
+	Sentence Platform = "conversation"
+	// https://www.home-assistant.io/docs/automation/trigger/#state-trigger
+	State Platform = "state"
+	// https://www.home-assistant.io/docs/automation/trigger/#sun-trigger
+	Sun Platform = "sun"
+	// https://www.home-assistant.io/docs/automation/trigger/#tag-trigger
+	Tag Platform = "tag"
+	// https://www.home-assistant.io/docs/automation/trigger/#template-trigger
+	Template Platform = "template"
+	// https://www.home-assistant.io/docs/automation/trigger/#time-trigger
+	Time Platform = "time"
+	// https://www.home-assistant.io/docs/automation/trigger/#time-pattern-trigger
+	TimePattern Platform = "time_pattern"
+	// https://www.home-assistant.io/docs/automation/trigger/#webhook-trigger
+	Webhook Platform = "webhook"
+	// https://www.home-assistant.io/docs/automation/trigger/#zone-trigger
+	Zone Platform = "zone"
+)
+
+func (p Platform) String() string {
+	return string(p)
+}
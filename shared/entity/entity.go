@@ -35,6 +35,24 @@ func (e *ID) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalText converts the EntityID to "domain.name", so IDs can be used as
+// keys of JSON objects.
+func (e ID) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText parses "domain.name", so IDs can be used as keys of JSON objects.
+func (e *ID) UnmarshalText(data []byte) error {
+	parsedEntityID, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	*e = parsedEntityID
+
+	return nil
+}
+
 // Domain returns the domain of the entity ID as a Domain type.
 func (e ID) Domain() domains.Domain {
 	return e.domain
@@ -60,6 +78,17 @@ func Parse(entityID string) (ID, error) {
 	return ID{domain: domains.Domain(parts[0]), name: parts[1]}, nil
 }
 
+// MustParse is like Parse but panics if the entity ID is invalid. It is meant
+// for package level variables, such as those generated by cmd/hagen.
+func MustParse(entityID string) ID {
+	id, err := Parse(entityID)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
 // IDList is a custom type for a list of entity IDs that marshals into []string.
 type IDList []ID
 
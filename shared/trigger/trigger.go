@@ -0,0 +1,211 @@
+// Typed Home Assistant triggers for use with subscribe_trigger.
+// https://www.home-assistant.io/docs/automation/trigger
+
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/platforms"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+// Trigger is implemented by every typed trigger in this package.
+type Trigger interface {
+	Platform() platforms.Platform
+}
+
+type (
+	// State fires when the state or an attribute of one or more entities changes.
+	State struct {
+		ID        string        `json:"id,omitempty"`
+		EntityID  entity.IDList `json:"entity_id"`
+		Attribute string        `json:"attribute,omitempty"`
+		From      []string      `json:"from,omitempty"`
+		To        []string      `json:"to,omitempty"`
+		NotFrom   []string      `json:"not_from,omitempty"`
+		NotTo     []string      `json:"not_to,omitempty"`
+		For       time.Duration `json:"-"`
+	}
+
+	// NumericState fires when the numeric value of an entity crosses a threshold.
+	NumericState struct {
+		ID            string        `json:"id,omitempty"`
+		EntityID      entity.IDList `json:"entity_id"`
+		Attribute     string        `json:"attribute,omitempty"`
+		Above         *float64      `json:"above,omitempty"`
+		Below         *float64      `json:"below,omitempty"`
+		ValueTemplate string        `json:"value_template,omitempty"`
+		For           time.Duration `json:"-"`
+	}
+
+	// Time fires at a time of day, given as "HH:MM:SS" or a datetime entity ID.
+	Time struct {
+		ID string   `json:"id,omitempty"`
+		At []string `json:"at"`
+	}
+
+	// TimePattern fires whenever the hours, minutes or seconds match, e.g. "/5".
+	TimePattern struct {
+		ID      string `json:"id,omitempty"`
+		Hours   string `json:"hours,omitempty"`
+		Minutes string `json:"minutes,omitempty"`
+		Seconds string `json:"seconds,omitempty"`
+	}
+
+	// Sun fires on sunrise or sunset, optionally offset.
+	Sun struct {
+		ID     string        `json:"id,omitempty"`
+		Event  SunEvent      `json:"event"`
+		Offset time.Duration `json:"-"`
+	}
+
+	// Zone fires when an entity enters or leaves a zone.
+	Zone struct {
+		ID       string        `json:"id,omitempty"`
+		EntityID entity.IDList `json:"entity_id"`
+		Zone     entity.ID     `json:"zone"`
+		Event    ZoneEvent     `json:"event"`
+	}
+
+	// Template fires when a template renders true after having rendered false.
+	Template struct {
+		ID            string        `json:"id,omitempty"`
+		ValueTemplate string        `json:"value_template"`
+		For           time.Duration `json:"-"`
+	}
+
+	// Event fires when an event of one of the types is fired, optionally matching data.
+	Event struct {
+		ID        string         `json:"id,omitempty"`
+		EventType []string       `json:"event_type"`
+		EventData map[string]any `json:"event_data,omitempty"`
+		Context   map[string]any `json:"context,omitempty"`
+	}
+
+	// MQTT fires when a message is received on a topic, optionally matching the payload.
+	MQTT struct {
+		ID            string `json:"id,omitempty"`
+		Topic         string `json:"topic"`
+		Payload       string `json:"payload,omitempty"`
+		ValueTemplate string `json:"value_template,omitempty"`
+		Encoding      string `json:"encoding,omitempty"`
+		QoS           *int   `json:"qos,omitempty"`
+	}
+
+	// Webhook fires when a request is received on /api/webhook/<webhook_id>.
+	Webhook struct {
+		ID             string   `json:"id,omitempty"`
+		WebhookID      string   `json:"webhook_id"`
+		AllowedMethods []string `json:"allowed_methods,omitempty"`
+		LocalOnly      *bool    `json:"local_only,omitempty"`
+	}
+
+	SunEvent  string
+	ZoneEvent string
+)
+
+const (
+	Sunrise SunEvent = "sunrise"
+	Sunset  SunEvent = "sunset"
+
+	Enter ZoneEvent = "enter"
+	Leave ZoneEvent = "leave"
+)
+
+func (State) Platform() platforms.Platform        { return platforms.State }
+func (NumericState) Platform() platforms.Platform { return platforms.NumericState }
+func (Time) Platform() platforms.Platform         { return platforms.Time }
+func (TimePattern) Platform() platforms.Platform  { return platforms.TimePattern }
+func (Sun) Platform() platforms.Platform          { return platforms.Sun }
+func (Zone) Platform() platforms.Platform         { return platforms.Zone }
+func (Template) Platform() platforms.Platform     { return platforms.Template }
+func (Event) Platform() platforms.Platform        { return platforms.Event }
+func (MQTT) Platform() platforms.Platform         { return platforms.MQTT }
+func (Webhook) Platform() platforms.Platform      { return platforms.Webhook }
+
+// MarshalJSON adds the platform and the "for" duration to the trigger.
+func (t State) MarshalJSON() ([]byte, error) {
+	type alias State
+	return marshal(t, alias(t), map[string]time.Duration{"for": t.For})
+}
+
+// MarshalJSON adds the platform and the "for" duration to the trigger.
+func (t NumericState) MarshalJSON() ([]byte, error) {
+	type alias NumericState
+	return marshal(t, alias(t), map[string]time.Duration{"for": t.For})
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t Time) MarshalJSON() ([]byte, error) {
+	type alias Time
+	return marshal(t, alias(t), nil)
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t TimePattern) MarshalJSON() ([]byte, error) {
+	type alias TimePattern
+	return marshal(t, alias(t), nil)
+}
+
+// MarshalJSON adds the platform and the offset to the trigger.
+func (t Sun) MarshalJSON() ([]byte, error) {
+	type alias Sun
+	return marshal(t, alias(t), map[string]time.Duration{"offset": t.Offset})
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t Zone) MarshalJSON() ([]byte, error) {
+	type alias Zone
+	return marshal(t, alias(t), nil)
+}
+
+// MarshalJSON adds the platform and the "for" duration to the trigger.
+func (t Template) MarshalJSON() ([]byte, error) {
+	type alias Template
+	return marshal(t, alias(t), map[string]time.Duration{"for": t.For})
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t Event) MarshalJSON() ([]byte, error) {
+	type alias Event
+	return marshal(t, alias(t), nil)
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t MQTT) MarshalJSON() ([]byte, error) {
+	type alias MQTT
+	return marshal(t, alias(t), nil)
+}
+
+// MarshalJSON adds the platform to the trigger.
+func (t Webhook) MarshalJSON() ([]byte, error) {
+	type alias Webhook
+	return marshal(t, alias(t), nil)
+}
+
+// Marshal the fields of a trigger and add its platform. Durations are sent as
+// seconds and left out when zero.
+func marshal(t Trigger, fields any, durations map[string]time.Duration) ([]byte, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to marshal %s trigger: %w", t.Platform(), err)
+	}
+
+	m["platform"] = t.Platform()
+
+	for key, duration := range durations {
+		if duration != 0 {
+			m[key] = duration.Seconds()
+		}
+	}
+
+	return json.Marshal(m)
+}
@@ -0,0 +1,52 @@
+package trigger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	light, err := entity.Parse("light.kitchen")
+	assert.NoError(t, err)
+
+	above := 20.5
+
+	tests := []struct {
+		name     string
+		trigger  Trigger
+		expected string
+	}{
+		{
+			name:     "State",
+			trigger:  State{EntityID: entity.IDList{light}, To: []string{"on"}, For: 90 * time.Second},
+			expected: `{"platform":"state","entity_id":["light.kitchen"],"to":["on"],"for":90}`,
+		},
+		{
+			name:     "NumericState",
+			trigger:  NumericState{EntityID: entity.IDList{light}, Attribute: "brightness", Above: &above},
+			expected: `{"platform":"numeric_state","entity_id":["light.kitchen"],"attribute":"brightness","above":20.5}`,
+		},
+		{
+			name:     "Sun",
+			trigger:  Sun{Event: Sunset, Offset: -time.Hour},
+			expected: `{"platform":"sun","event":"sunset","offset":-3600}`,
+		},
+		{
+			name:     "TimePattern",
+			trigger:  TimePattern{ID: "every_5", Minutes: "/5"},
+			expected: `{"platform":"time_pattern","id":"every_5","minutes":"/5"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.trigger)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// ClimateState is a climate entity with its attributes parsed. The state
+	// is the current HVAC mode.
+	// https://www.home-assistant.io/integrations/climate
+	ClimateState struct {
+		Entity
+		ClimateAttributes
+	}
+
+	ClimateAttributes struct {
+		CommonAttributes
+		SupportedFeatures    ClimateFeature `json:"supported_features"`
+		HVACModes            []HVACMode     `json:"hvac_modes"`
+		HVACAction           *HVACAction    `json:"hvac_action"`
+		MinTemp              *float64       `json:"min_temp"`
+		MaxTemp              *float64       `json:"max_temp"`
+		TargetTempStep       *float64       `json:"target_temp_step"`
+		CurrentTemperature   *float64       `json:"current_temperature"`
+		Temperature          *float64       `json:"temperature"` // Target temperature
+		TargetTempHigh       *float64       `json:"target_temp_high"`
+		TargetTempLow        *float64       `json:"target_temp_low"`
+		CurrentHumidity      *float64       `json:"current_humidity"`
+		Humidity             *float64       `json:"humidity"` // Target humidity
+		MinHumidity          *float64       `json:"min_humidity"`
+		MaxHumidity          *float64       `json:"max_humidity"`
+		FanMode              *string        `json:"fan_mode"`
+		FanModes             []string       `json:"fan_modes"`
+		PresetMode           *string        `json:"preset_mode"`
+		PresetModes          []string       `json:"preset_modes"`
+		SwingMode            *string        `json:"swing_mode"`
+		SwingModes           []string       `json:"swing_modes"`
+		SwingHorizontalMode  *string        `json:"swing_horizontal_mode"`
+		SwingHorizontalModes []string       `json:"swing_horizontal_modes"`
+	}
+
+	// ClimateFeature is a bitmask of the features a climate entity supports.
+	ClimateFeature int
+
+	HVACMode string
+
+	HVACAction string
+)
+
+const (
+	ClimateFeatureTargetTemperature      ClimateFeature = 1
+	ClimateFeatureTargetTemperatureRange ClimateFeature = 2
+	ClimateFeatureTargetHumidity         ClimateFeature = 4
+	ClimateFeatureFanMode                ClimateFeature = 8
+	ClimateFeaturePresetMode             ClimateFeature = 16
+	ClimateFeatureSwingMode              ClimateFeature = 32
+	ClimateFeatureTurnOff                ClimateFeature = 128
+	ClimateFeatureTurnOn                 ClimateFeature = 256
+	ClimateFeatureSwingHorizontalMode    ClimateFeature = 512
+)
+
+const (
+	HVACModeOff      HVACMode = "off"
+	HVACModeHeat     HVACMode = "heat"
+	HVACModeCool     HVACMode = "cool"
+	HVACModeHeatCool HVACMode = "heat_cool"
+	HVACModeAuto     HVACMode = "auto"
+	HVACModeDry      HVACMode = "dry"
+	HVACModeFanOnly  HVACMode = "fan_only"
+)
+
+const (
+	HVACActionOff        HVACAction = "off"
+	HVACActionPreheating HVACAction = "preheating"
+	HVACActionHeating    HVACAction = "heating"
+	HVACActionCooling    HVACAction = "cooling"
+	HVACActionDrying     HVACAction = "drying"
+	HVACActionIdle       HVACAction = "idle"
+	HVACActionFan        HVACAction = "fan"
+	HVACActionDefrosting HVACAction = "defrosting"
+)
+
+// NewClimateState parses the attributes of a climate entity.
+func NewClimateState(e Entity) (ClimateState, error) {
+	s := ClimateState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Climate, &s.ClimateAttributes)
+}
+
+// HVACMode returns the current HVAC mode.
+func (s ClimateState) HVACMode() HVACMode {
+	return HVACMode(s.State)
+}
+
+// Supports reports whether the climate entity supports every feature in f.
+func (a ClimateAttributes) Supports(f ClimateFeature) bool {
+	return a.SupportedFeatures&f == f
+}
@@ -0,0 +1,55 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// CoverState is a cover entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/cover
+	CoverState struct {
+		Entity
+		CoverAttributes
+	}
+
+	CoverAttributes struct {
+		CommonAttributes
+		SupportedFeatures   CoverFeature `json:"supported_features"`
+		CurrentPosition     *int         `json:"current_position"`      // 0 is closed, 100 is fully open
+		CurrentTiltPosition *int         `json:"current_tilt_position"` // 0 is closed, 100 is fully open
+	}
+
+	// CoverFeature is a bitmask of the features a cover supports.
+	CoverFeature int
+)
+
+const (
+	CoverFeatureOpen            CoverFeature = 1
+	CoverFeatureClose           CoverFeature = 2
+	CoverFeatureSetPosition     CoverFeature = 4
+	CoverFeatureStop            CoverFeature = 8
+	CoverFeatureOpenTilt        CoverFeature = 16
+	CoverFeatureCloseTilt       CoverFeature = 32
+	CoverFeatureStopTilt        CoverFeature = 64
+	CoverFeatureSetTiltPosition CoverFeature = 128
+)
+
+// NewCoverState parses the attributes of a cover entity.
+func NewCoverState(e Entity) (CoverState, error) {
+	s := CoverState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Cover, &s.CoverAttributes)
+}
+
+// IsOpen reports whether the cover is open or opening.
+func (s CoverState) IsOpen() bool {
+	return s.State == "open" || s.State == "opening"
+}
+
+// IsClosed reports whether the cover is closed.
+func (s CoverState) IsClosed() bool {
+	return s.State == "closed"
+}
+
+// Supports reports whether the cover supports every feature in f.
+func (a CoverAttributes) Supports(f CoverFeature) bool {
+	return a.SupportedFeatures&f == f
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+// CommonAttributes are the attributes shared by entities of every domain.
+type CommonAttributes struct {
+	FriendlyName  string `json:"friendly_name"`
+	Icon          string `json:"icon"`
+	EntityPicture string `json:"entity_picture"`
+	AssumedState  bool   `json:"assumed_state"`
+	DeviceClass   string `json:"device_class"`
+}
+
+// Check the domain of an entity and parse its attributes into attributes.
+func decodeDomainState(e Entity, domain domains.Domain, attributes any) error {
+	if e.EntityID.Domain() != domain {
+		return fmt.Errorf("entity %s is not in the %s domain", e.EntityID, domain)
+	}
+
+	if e.Attributes == nil {
+		return nil
+	}
+
+	if err := e.UnmarshalAttributes(attributes); err != nil {
+		return fmt.Errorf("failed to parse %s attributes: %w", e.EntityID, err)
+	}
+
+	return nil
+}
+
+// Look up an entity and convert it to a typed domain state.
+func domainState[T any](e EntitiesMap, id entity.ID, newState func(Entity) (T, error)) (T, error) {
+	if err := e.Exists(id); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return newState(e[id])
+}
+
+// Light returns the state of a light entity.
+func (e EntitiesMap) Light(id entity.ID) (LightState, error) {
+	return domainState(e, id, NewLightState)
+}
+
+// Switch returns the state of a switch entity.
+func (e EntitiesMap) Switch(id entity.ID) (SwitchState, error) {
+	return domainState(e, id, NewSwitchState)
+}
+
+// BinarySensor returns the state of a binary sensor entity.
+func (e EntitiesMap) BinarySensor(id entity.ID) (BinarySensorState, error) {
+	return domainState(e, id, NewBinarySensorState)
+}
+
+// Sensor returns the state of a sensor entity.
+func (e EntitiesMap) Sensor(id entity.ID) (SensorState, error) {
+	return domainState(e, id, NewSensorState)
+}
+
+// Climate returns the state of a climate entity.
+func (e EntitiesMap) Climate(id entity.ID) (ClimateState, error) {
+	return domainState(e, id, NewClimateState)
+}
+
+// Cover returns the state of a cover entity.
+func (e EntitiesMap) Cover(id entity.ID) (CoverState, error) {
+	return domainState(e, id, NewCoverState)
+}
+
+// Fan returns the state of a fan entity.
+func (e EntitiesMap) Fan(id entity.ID) (FanState, error) {
+	return domainState(e, id, NewFanState)
+}
+
+// Lock returns the state of a lock entity.
+func (e EntitiesMap) Lock(id entity.ID) (LockState, error) {
+	return domainState(e, id, NewLockState)
+}
+
+// MediaPlayer returns the state of a media player entity.
+func (e EntitiesMap) MediaPlayer(id entity.ID) (MediaPlayerState, error) {
+	return domainState(e, id, NewMediaPlayerState)
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustEntity(t *testing.T, raw string) Entity {
+	t.Helper()
+
+	var e Entity
+	require.NoError(t, json.Unmarshal([]byte(raw), &e))
+
+	return e
+}
+
+func TestLightState(t *testing.T) {
+	e := mustEntity(t, `{"entity_id": "light.kitchen", "state": "on", "attributes": {
+		"friendly_name": "Kitchen", "supported_features": 44, "color_mode": "color_temp",
+		"supported_color_modes": ["color_temp", "xy"], "brightness": 180, "color_temp_kelvin": 2700,
+		"effect_list": ["colorloop"], "effect": null
+	}}`)
+
+	light, err := NewLightState(e)
+	require.NoError(t, err)
+
+	assert.True(t, light.IsOn())
+	assert.Equal(t, "Kitchen", light.FriendlyName)
+	assert.Equal(t, 180, *light.Brightness)
+	assert.Equal(t, ColorModeColorTemp, *light.ColorMode)
+	assert.True(t, light.SupportsColorMode(ColorModeXY))
+	assert.False(t, light.SupportsColorMode(ColorModeRGB))
+	assert.True(t, light.Supports(LightFeatureEffect|LightFeatureTransition))
+	assert.Nil(t, light.Effect)
+
+	_, err = NewClimateState(e)
+	assert.Error(t, err)
+}
+
+func TestClimateState(t *testing.T) {
+	e := mustEntity(t, `{"entity_id": "climate.hallway", "state": "heat_cool", "attributes": {
+		"hvac_modes": ["off", "heat", "cool", "heat_cool"], "hvac_action": "heating",
+		"current_temperature": 19.5, "temperature": null, "target_temp_low": 20, "target_temp_high": 24,
+		"supported_features": 386
+	}}`)
+
+	climate, err := NewClimateState(e)
+	require.NoError(t, err)
+
+	assert.Equal(t, HVACModeHeatCool, climate.HVACMode())
+	assert.Equal(t, HVACActionHeating, *climate.HVACAction)
+	assert.Contains(t, climate.HVACModes, HVACModeCool)
+	assert.InDelta(t, 19.5, *climate.CurrentTemperature, 0)
+	assert.Nil(t, climate.Temperature)
+	assert.InDelta(t, 20.0, *climate.TargetTempLow, 0)
+	assert.True(t, climate.Supports(ClimateFeatureTargetTemperatureRange))
+	assert.False(t, climate.Supports(ClimateFeatureTargetTemperature))
+}
+
+func TestEntitiesMapAccessors(t *testing.T) {
+	entities := Entities{
+		mustEntity(t, `{"entity_id": "sensor.power", "state": "412.5", "attributes": {"state_class": "measurement", "unit_of_measurement": "W", "device_class": "power"}}`),
+		mustEntity(t, `{"entity_id": "cover.garage", "state": "open", "attributes": {"current_position": 100, "supported_features": 15}}`),
+	}.SortStates()
+
+	power, err := entity.Parse("sensor.power")
+	require.NoError(t, err)
+
+	sensor, err := entities.Sensor(power)
+	require.NoError(t, err)
+
+	value, err := sensor.Float()
+	require.NoError(t, err)
+	assert.InDelta(t, 412.5, value, 0)
+	assert.Equal(t, "power", sensor.DeviceClass)
+	assert.Equal(t, SensorStateClassMeasurement, *sensor.StateClass)
+
+	garage, err := entity.Parse("cover.garage")
+	require.NoError(t, err)
+
+	cover, err := entities.Cover(garage)
+	require.NoError(t, err)
+	assert.True(t, cover.IsOpen())
+	assert.True(t, cover.Supports(CoverFeatureSetPosition|CoverFeatureStop))
+	assert.False(t, cover.Supports(CoverFeatureSetTiltPosition))
+
+	_, err = entities.Light(garage)
+	assert.Error(t, err)
+
+	missing, err := entity.Parse("light.missing")
+	require.NoError(t, err)
+
+	_, err = entities.Light(missing)
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// FanState is a fan entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/fan
+	FanState struct {
+		Entity
+		FanAttributes
+	}
+
+	FanAttributes struct {
+		CommonAttributes
+		SupportedFeatures FanFeature `json:"supported_features"`
+		Percentage        *int       `json:"percentage"`
+		PercentageStep    *float64   `json:"percentage_step"`
+		Oscillating       *bool      `json:"oscillating"`
+		Direction         *string    `json:"direction"` // forward or reverse
+		PresetMode        *string    `json:"preset_mode"`
+		PresetModes       []string   `json:"preset_modes"`
+	}
+
+	// FanFeature is a bitmask of the features a fan supports.
+	FanFeature int
+)
+
+const (
+	FanFeatureSetSpeed   FanFeature = 1
+	FanFeatureOscillate  FanFeature = 2
+	FanFeatureDirection  FanFeature = 4
+	FanFeaturePresetMode FanFeature = 8
+	FanFeatureTurnOff    FanFeature = 16
+	FanFeatureTurnOn     FanFeature = 32
+)
+
+// NewFanState parses the attributes of a fan entity.
+func NewFanState(e Entity) (FanState, error) {
+	s := FanState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Fan, &s.FanAttributes)
+}
+
+// IsOn reports whether the fan is on.
+func (s FanState) IsOn() bool {
+	return s.State == "on"
+}
+
+// Supports reports whether the fan supports every feature in f.
+func (a FanAttributes) Supports(f FanFeature) bool {
+	return a.SupportedFeatures&f == f
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+)
+
+type (
+	// History holds the states of each entity over a period, oldest first.
+	History map[entity.ID][]HistoryState
+
+	// HistoryState is a state in the compressed history format of the
+	// websocket API. Attributes are not set when requested without attributes
+	// and, for minimal responses, on every state after the first.
+	HistoryState struct {
+		State       state.Value
+		Attributes  json.RawMessage
+		LastChanged time.Time
+		LastUpdated time.Time
+	}
+
+	// HistoryStream is a message of a history stream. The first message holds
+	// the states up to now, later ones the states since the previous message.
+	HistoryStream struct {
+		States    History  `json:"states"`
+		StartTime UnixTime `json:"start_time"`
+		EndTime   UnixTime `json:"end_time"`
+	}
+
+	// LogbookEvent is a logbook entry as sent by the websocket API.
+	LogbookEvent struct {
+		When             UnixTime   `json:"when"`
+		EntityID         *entity.ID `json:"entity_id"`
+		State            *string    `json:"state"`
+		Name             string     `json:"name"`
+		Message          string     `json:"message"`
+		Domain           string     `json:"domain"`
+		Icon             string     `json:"icon"`
+		ContextID        string     `json:"context_id"`
+		ContextUserID    *string    `json:"context_user_id"`
+		ContextEventType string     `json:"context_event_type"`
+		ContextDomain    string     `json:"context_domain"`
+		ContextService   string     `json:"context_service"`
+		ContextEntityID  *entity.ID `json:"context_entity_id"`
+		ContextName      string     `json:"context_name"`
+		ContextMessage   string     `json:"context_message"`
+	}
+
+	// LogbookStream is a message of a logbook stream. Partial is set while
+	// older events are still being sent.
+	LogbookStream struct {
+		Events    []LogbookEvent `json:"events"`
+		StartTime UnixTime       `json:"start_time"`
+		EndTime   UnixTime       `json:"end_time"`
+		Partial   bool           `json:"partial"`
+	}
+)
+
+// UnmarshalJSON decodes the short keys of the compressed format. last_changed
+// is only sent when it differs from last_updated.
+func (h *HistoryState) UnmarshalJSON(data []byte) error {
+	var compressed struct {
+		State       state.Value     `json:"s"`
+		Attributes  json.RawMessage `json:"a"`
+		LastChanged *UnixTime       `json:"lc"`
+		LastUpdated UnixTime        `json:"lu"`
+	}
+
+	if err := json.Unmarshal(data, &compressed); err != nil {
+		return err
+	}
+
+	*h = HistoryState{
+		State:       compressed.State,
+		Attributes:  compressed.Attributes,
+		LastUpdated: compressed.LastUpdated.Time().UTC(),
+	}
+
+	h.LastChanged = h.LastUpdated
+	if compressed.LastChanged != nil {
+		h.LastChanged = compressed.LastChanged.Time().UTC()
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// LightState is a light entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/light
+	LightState struct {
+		Entity
+		LightAttributes
+	}
+
+	LightAttributes struct {
+		CommonAttributes
+		SupportedFeatures   LightFeature `json:"supported_features"`
+		ColorMode           *ColorMode   `json:"color_mode"`
+		SupportedColorModes []ColorMode  `json:"supported_color_modes"`
+		Brightness          *int         `json:"brightness"` // 0-255
+		ColorTempKelvin     *int         `json:"color_temp_kelvin"`
+		MinColorTempKelvin  *int         `json:"min_color_temp_kelvin"`
+		MaxColorTempKelvin  *int         `json:"max_color_temp_kelvin"`
+		HSColor             []float64    `json:"hs_color"`
+		RGBColor            []int        `json:"rgb_color"`
+		RGBWColor           []int        `json:"rgbw_color"`
+		RGBWWColor          []int        `json:"rgbww_color"`
+		XYColor             []float64    `json:"xy_color"`
+		Effect              *string      `json:"effect"`
+		EffectList          []string     `json:"effect_list"`
+	}
+
+	// LightFeature is a bitmask of the features a light supports.
+	LightFeature int
+
+	ColorMode string
+)
+
+const (
+	LightFeatureEffect     LightFeature = 4
+	LightFeatureFlash      LightFeature = 8
+	LightFeatureTransition LightFeature = 32
+)
+
+const (
+	ColorModeUnknown    ColorMode = "unknown"
+	ColorModeOnOff      ColorMode = "onoff"
+	ColorModeBrightness ColorMode = "brightness"
+	ColorModeColorTemp  ColorMode = "color_temp"
+	ColorModeHS         ColorMode = "hs"
+	ColorModeXY         ColorMode = "xy"
+	ColorModeRGB        ColorMode = "rgb"
+	ColorModeRGBW       ColorMode = "rgbw"
+	ColorModeRGBWW      ColorMode = "rgbww"
+	ColorModeWhite      ColorMode = "white"
+)
+
+// NewLightState parses the attributes of a light entity.
+func NewLightState(e Entity) (LightState, error) {
+	s := LightState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Light, &s.LightAttributes)
+}
+
+// IsOn reports whether the light is on.
+func (s LightState) IsOn() bool {
+	return s.State == "on"
+}
+
+// Supports reports whether the light supports every feature in f.
+func (a LightAttributes) Supports(f LightFeature) bool {
+	return a.SupportedFeatures&f == f
+}
+
+// SupportsColorMode reports whether the light supports a color mode.
+func (a LightAttributes) SupportsColorMode(mode ColorMode) bool {
+	for _, m := range a.SupportedColorModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// LockState is a lock entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/lock
+	LockState struct {
+		Entity
+		LockAttributes
+	}
+
+	LockAttributes struct {
+		CommonAttributes
+		SupportedFeatures LockFeature `json:"supported_features"`
+		ChangedBy         *string     `json:"changed_by"`
+		CodeFormat        *string     `json:"code_format"`
+	}
+
+	// LockFeature is a bitmask of the features a lock supports.
+	LockFeature int
+)
+
+const (
+	LockFeatureOpen LockFeature = 1
+)
+
+// NewLockState parses the attributes of a lock entity.
+func NewLockState(e Entity) (LockState, error) {
+	s := LockState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Lock, &s.LockAttributes)
+}
+
+// IsLocked reports whether the lock is locked.
+func (s LockState) IsLocked() bool {
+	return s.State == "locked"
+}
+
+// IsJammed reports whether the lock is jammed.
+func (s LockState) IsJammed() bool {
+	return s.State == "jammed"
+}
+
+// Supports reports whether the lock supports every feature in f.
+func (a LockAttributes) Supports(f LockFeature) bool {
+	return a.SupportedFeatures&f == f
+}
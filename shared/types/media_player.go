@@ -0,0 +1,91 @@
+package types
+
+import (
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+)
+
+type (
+	// MediaPlayerState is a media player entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/media_player
+	MediaPlayerState struct {
+		Entity
+		MediaPlayerAttributes
+	}
+
+	MediaPlayerAttributes struct {
+		CommonAttributes
+		SupportedFeatures      MediaPlayerFeature `json:"supported_features"`
+		VolumeLevel            *float64           `json:"volume_level"` // 0-1
+		IsVolumeMuted          *bool              `json:"is_volume_muted"`
+		MediaContentID         *string            `json:"media_content_id"`
+		MediaContentType       *string            `json:"media_content_type"`
+		MediaDuration          *float64           `json:"media_duration"` // Seconds
+		MediaPosition          *float64           `json:"media_position"` // Seconds
+		MediaPositionUpdatedAt *time.Time         `json:"media_position_updated_at"`
+		MediaTitle             *string            `json:"media_title"`
+		MediaArtist            *string            `json:"media_artist"`
+		MediaAlbumName         *string            `json:"media_album_name"`
+		MediaAlbumArtist       *string            `json:"media_album_artist"`
+		MediaTrack             *int               `json:"media_track"`
+		AppName                *string            `json:"app_name"`
+		Source                 *string            `json:"source"`
+		SourceList             []string           `json:"source_list"`
+		SoundMode              *string            `json:"sound_mode"`
+		SoundModeList          []string           `json:"sound_mode_list"`
+		Shuffle                *bool              `json:"shuffle"`
+		Repeat                 *string            `json:"repeat"` // off, all or one
+		GroupMembers           []string           `json:"group_members"`
+	}
+
+	// MediaPlayerFeature is a bitmask of the features a media player supports.
+	MediaPlayerFeature int
+)
+
+const (
+	MediaPlayerFeaturePause           MediaPlayerFeature = 1
+	MediaPlayerFeatureSeek            MediaPlayerFeature = 2
+	MediaPlayerFeatureVolumeSet       MediaPlayerFeature = 4
+	MediaPlayerFeatureVolumeMute      MediaPlayerFeature = 8
+	MediaPlayerFeaturePreviousTrack   MediaPlayerFeature = 16
+	MediaPlayerFeatureNextTrack       MediaPlayerFeature = 32
+	MediaPlayerFeatureTurnOn          MediaPlayerFeature = 128
+	MediaPlayerFeatureTurnOff         MediaPlayerFeature = 256
+	MediaPlayerFeaturePlayMedia       MediaPlayerFeature = 512
+	MediaPlayerFeatureVolumeStep      MediaPlayerFeature = 1024
+	MediaPlayerFeatureSelectSource    MediaPlayerFeature = 2048
+	MediaPlayerFeatureStop            MediaPlayerFeature = 4096
+	MediaPlayerFeatureClearPlaylist   MediaPlayerFeature = 8192
+	MediaPlayerFeaturePlay            MediaPlayerFeature = 16384
+	MediaPlayerFeatureShuffleSet      MediaPlayerFeature = 32768
+	MediaPlayerFeatureSelectSoundMode MediaPlayerFeature = 65536
+	MediaPlayerFeatureBrowseMedia     MediaPlayerFeature = 131072
+	MediaPlayerFeatureRepeatSet       MediaPlayerFeature = 262144
+	MediaPlayerFeatureGrouping        MediaPlayerFeature = 524288
+	MediaPlayerFeatureMediaAnnounce   MediaPlayerFeature = 1048576
+	MediaPlayerFeatureMediaEnqueue    MediaPlayerFeature = 2097152
+	MediaPlayerFeatureSearchMedia     MediaPlayerFeature = 4194304
+)
+
+// NewMediaPlayerState parses the attributes of a media player entity.
+func NewMediaPlayerState(e Entity) (MediaPlayerState, error) {
+	s := MediaPlayerState{Entity: e}
+
+	return s, decodeDomainState(e, domains.MediaPlayer, &s.MediaPlayerAttributes)
+}
+
+// IsPlaying reports whether the media player is playing.
+func (s MediaPlayerState) IsPlaying() bool {
+	return s.State == "playing"
+}
+
+// IsOff reports whether the media player is off or in standby.
+func (s MediaPlayerState) IsOff() bool {
+	return s.State == "off" || s.State == "standby"
+}
+
+// Supports reports whether the media player supports every feature in f.
+func (a MediaPlayerAttributes) Supports(f MediaPlayerFeature) bool {
+	return a.SupportedFeatures&f == f
+}
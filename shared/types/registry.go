@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+type (
+	// UnixTime is a timestamp sent as fractional seconds since the Unix epoch.
+	UnixTime float64
+
+	// EntityRegistryEntry is an entity as returned by config/entity_registry/list.
+	EntityRegistryEntry struct {
+		ID             string                     `json:"id"`
+		EntityID       entity.ID                  `json:"entity_id"`
+		UniqueID       string                     `json:"unique_id"`
+		Platform       string                     `json:"platform"`
+		ConfigEntryID  *string                    `json:"config_entry_id"`
+		DeviceID       *string                    `json:"device_id"`
+		AreaID         *string                    `json:"area_id"`
+		Labels         []string                   `json:"labels"`
+		Categories     map[string]string          `json:"categories"`
+		DisabledBy     *DisabledBy                `json:"disabled_by"`
+		HiddenBy       *HiddenBy                  `json:"hidden_by"`
+		EntityCategory *string                    `json:"entity_category"`
+		HasEntityName  bool                       `json:"has_entity_name"`
+		Name           *string                    `json:"name"` // Set by the user, overrides OriginalName
+		OriginalName   *string                    `json:"original_name"`
+		Icon           *string                    `json:"icon"`
+		TranslationKey *string                    `json:"translation_key"`
+		Options        map[string]json.RawMessage `json:"options"`
+		CreatedAt      UnixTime                   `json:"created_at"`
+		ModifiedAt     UnixTime                   `json:"modified_at"`
+	}
+
+	// EntityRegistryEntryExtended is an entity as returned by
+	// config/entity_registry/get, which includes more details than the list.
+	EntityRegistryEntryExtended struct {
+		EntityRegistryEntry
+		Aliases             []string       `json:"aliases"`
+		Capabilities        map[string]any `json:"capabilities"`
+		DeviceClass         *string        `json:"device_class"`
+		OriginalDeviceClass *string        `json:"original_device_class"`
+		OriginalIcon        *string        `json:"original_icon"`
+	}
+
+	// EntityRegistryUpdateResult is returned by config/entity_registry/update.
+	EntityRegistryUpdateResult struct {
+		EntityEntry    EntityRegistryEntryExtended `json:"entity_entry"`
+		RequireRestart bool                        `json:"require_restart"` // Set when enabling an entity needs a restart
+		ReloadDelay    *int                        `json:"reload_delay"`    // Seconds until the config entry reloads after enabling
+	}
+
+	// DisabledBy is what disabled a registry entry.
+	DisabledBy string
+
+	// HiddenBy is what hid a registry entry.
+	HiddenBy string
+)
+
+const (
+	DisabledByConfigEntry DisabledBy = "config_entry"
+	DisabledByDevice      DisabledBy = "device"
+	DisabledByHass        DisabledBy = "hass"
+	DisabledByIntegration DisabledBy = "integration"
+	DisabledByUser        DisabledBy = "user"
+)
+
+const (
+	HiddenByIntegration HiddenBy = "integration"
+	HiddenByUser        HiddenBy = "user"
+)
+
+// Time converts the timestamp to a time.Time.
+func (t UnixTime) Time() time.Time {
+	// Home Assistant timestamps have microsecond precision, rounding drops
+	// float noise below that
+	return time.UnixMicro(int64(math.Round(float64(t) * 1e6)))
+}
+
+type (
+	// Device is an entry in the device registry.
+	Device struct {
+		ID                 string      `json:"id"`
+		Name               *string     `json:"name"`
+		NameByUser         *string     `json:"name_by_user"` // Set by the user, overrides Name
+		AreaID             *string     `json:"area_id"`
+		Labels             []string    `json:"labels"`
+		ConfigEntries      []string    `json:"config_entries"`
+		PrimaryConfigEntry *string     `json:"primary_config_entry"`
+		Connections        [][2]string `json:"connections"` // Type and value pairs, such as a MAC address
+		Identifiers        [][2]string `json:"identifiers"` // Domain and ID pairs
+		Manufacturer       *string     `json:"manufacturer"`
+		Model              *string     `json:"model"`
+		ModelID            *string     `json:"model_id"`
+		HWVersion          *string     `json:"hw_version"`
+		SWVersion          *string     `json:"sw_version"`
+		SerialNumber       *string     `json:"serial_number"`
+		ConfigurationURL   *string     `json:"configuration_url"`
+		EntryType          *string     `json:"entry_type"`
+		DisabledBy         *DisabledBy `json:"disabled_by"`
+		ViaDeviceID        *string     `json:"via_device_id"`
+		CreatedAt          UnixTime    `json:"created_at"`
+		ModifiedAt         UnixTime    `json:"modified_at"`
+	}
+
+	// Area is an entry in the area registry.
+	Area struct {
+		AreaID              string     `json:"area_id"`
+		Name                string     `json:"name"`
+		FloorID             *string    `json:"floor_id"`
+		Aliases             []string   `json:"aliases"`
+		Labels              []string   `json:"labels"`
+		Icon                *string    `json:"icon"`
+		Picture             *string    `json:"picture"`
+		HumidityEntityID    *entity.ID `json:"humidity_entity_id"`
+		TemperatureEntityID *entity.ID `json:"temperature_entity_id"`
+		CreatedAt           UnixTime   `json:"created_at"`
+		ModifiedAt          UnixTime   `json:"modified_at"`
+	}
+
+	// Floor is an entry in the floor registry.
+	Floor struct {
+		FloorID    string   `json:"floor_id"`
+		Name       string   `json:"name"`
+		Level      *int     `json:"level"`
+		Aliases    []string `json:"aliases"`
+		Icon       *string  `json:"icon"`
+		CreatedAt  UnixTime `json:"created_at"`
+		ModifiedAt UnixTime `json:"modified_at"`
+	}
+
+	// Label is an entry in the label registry.
+	Label struct {
+		LabelID     string   `json:"label_id"`
+		Name        string   `json:"name"`
+		Color       *string  `json:"color"`
+		Description *string  `json:"description"`
+		Icon        *string  `json:"icon"`
+		CreatedAt   UnixTime `json:"created_at"`
+		ModifiedAt  UnixTime `json:"modified_at"`
+	}
+)
@@ -0,0 +1,232 @@
+package types
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+type (
+	// Registries holds registry entries to resolve targets and query against.
+	// Resolve only needs Entities, Devices and Areas as floors and labels are
+	// referenced from those.
+	Registries struct {
+		Entities []EntityRegistryEntry
+		Devices  []Device
+		Areas    []Area
+		Floors   []Floor
+		Labels   []Label
+	}
+
+	// EntityFilter selects entities in Registries.Filter. Every field that is
+	// set must match.
+	EntityFilter struct {
+		Domain   domains.Domain
+		DeviceID string
+		AreaID   string
+		FloorID  string
+		LabelID  string // Matches labels of the entity, its device or its area
+	}
+)
+
+// Resolve expands a target into the entities a service call would act on,
+// following the same rules as Home Assistant. Entities listed by ID are
+// always included. Entities only referenced through a device, area, floor or
+// label are skipped if they are hidden, disabled or have an entity category,
+// and an entity assigned to an area is only in that area, not in the area of
+// its device. The result is sorted and has no duplicates.
+func (r Registries) Resolve(target ServiceTarget) entity.IDList {
+	found := make(map[entity.ID]bool)
+
+	for _, id := range target.EntityID {
+		found[id] = true
+	}
+
+	areas := make(map[string]bool)
+	for _, id := range target.AreaID {
+		areas[id] = true
+	}
+
+	for _, a := range r.Areas {
+		if a.FloorID != nil && slices.Contains(target.FloorID, *a.FloorID) {
+			areas[a.AreaID] = true
+		}
+
+		if containsAny(a.Labels, target.LabelID) {
+			areas[a.AreaID] = true
+		}
+	}
+
+	devices := make(map[string]bool)
+	for _, id := range target.DeviceID {
+		devices[id] = true
+	}
+
+	devicesInArea := make(map[string]bool)
+
+	for _, d := range r.Devices {
+		if containsAny(d.Labels, target.LabelID) {
+			devices[d.ID] = true
+		}
+
+		if d.AreaID != nil && areas[*d.AreaID] {
+			devicesInArea[d.ID] = true
+		}
+	}
+
+	for _, e := range r.Entities {
+		if e.HiddenBy != nil || e.DisabledBy != nil || e.EntityCategory != nil {
+			continue
+		}
+
+		switch {
+		case containsAny(e.Labels, target.LabelID):
+		case e.DeviceID != nil && devices[*e.DeviceID]:
+		case e.AreaID != nil && areas[*e.AreaID]:
+		case e.AreaID == nil && e.DeviceID != nil && devicesInArea[*e.DeviceID]:
+		default:
+			continue
+		}
+
+		found[e.EntityID] = true
+	}
+
+	ids := make(entity.IDList, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+
+	return ids
+}
+
+// Filter returns the entities matching every field set in filter, sorted by
+// entity ID. An entity's area is its own or, if it has none, its device's.
+// Unlike Resolve, hidden and disabled entities are included.
+func (r Registries) Filter(filter EntityFilter) entity.IDList {
+	devices := make(map[string]Device, len(r.Devices))
+	for _, d := range r.Devices {
+		devices[d.ID] = d
+	}
+
+	areas := make(map[string]Area, len(r.Areas))
+	for _, a := range r.Areas {
+		areas[a.AreaID] = a
+	}
+
+	var ids entity.IDList
+
+	for _, e := range r.Entities {
+		var device Device
+		if e.DeviceID != nil {
+			device = devices[*e.DeviceID]
+		}
+
+		var area Area
+		if areaID := r.entityArea(e, device); areaID != nil {
+			area = areas[*areaID]
+		}
+
+		switch {
+		case filter.Domain != "" && e.EntityID.Domain() != filter.Domain:
+		case filter.DeviceID != "" && (e.DeviceID == nil || *e.DeviceID != filter.DeviceID):
+		case filter.AreaID != "" && area.AreaID != filter.AreaID:
+		case filter.FloorID != "" && (area.FloorID == nil || *area.FloorID != filter.FloorID):
+		case filter.LabelID != "" &&
+			!slices.Contains(e.Labels, filter.LabelID) &&
+			!slices.Contains(device.Labels, filter.LabelID) &&
+			!slices.Contains(area.Labels, filter.LabelID):
+		default:
+			ids = append(ids, e.EntityID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+
+	return ids
+}
+
+// The area of an entity, which is inherited from its device if it has none.
+func (r Registries) entityArea(e EntityRegistryEntry, device Device) *string {
+	if e.AreaID != nil {
+		return e.AreaID
+	}
+
+	return device.AreaID
+}
+
+// Entity returns the registry entry of an entity.
+func (r Registries) Entity(id entity.ID) (EntityRegistryEntry, bool) {
+	for _, e := range r.Entities {
+		if e.EntityID == id {
+			return e, true
+		}
+	}
+
+	return EntityRegistryEntry{}, false
+}
+
+// AreaByName returns the area with a name or alias, ignoring case.
+func (r Registries) AreaByName(name string) (Area, bool) {
+	for _, a := range r.Areas {
+		if matchesName(name, a.Name, a.Aliases) {
+			return a, true
+		}
+	}
+
+	return Area{}, false
+}
+
+// FloorByName returns the floor with a name or alias, ignoring case.
+func (r Registries) FloorByName(name string) (Floor, bool) {
+	for _, f := range r.Floors {
+		if matchesName(name, f.Name, f.Aliases) {
+			return f, true
+		}
+	}
+
+	return Floor{}, false
+}
+
+// LabelByName returns the label with a name, ignoring case.
+func (r Registries) LabelByName(name string) (Label, bool) {
+	for _, l := range r.Labels {
+		if matchesName(name, l.Name, nil) {
+			return l, true
+		}
+	}
+
+	return Label{}, false
+}
+
+func matchesName(name, candidate string, aliases []string) bool {
+	if strings.EqualFold(name, candidate) {
+		return true
+	}
+
+	for _, alias := range aliases {
+		if strings.EqualFold(name, alias) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range wanted {
+		if slices.Contains(values, v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistriesResolve(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	id := func(s string) entity.ID { return entity.MustParse(s) }
+	hidden := HiddenByUser
+
+	registries := Registries{
+		Areas: []Area{
+			{AreaID: "kitchen", FloorID: ptr("ground")},
+			{AreaID: "bedroom", FloorID: ptr("upstairs"), Labels: []string{"quiet"}},
+		},
+		Devices: []Device{
+			{ID: "hub", AreaID: ptr("kitchen")},
+			{ID: "lamp", AreaID: ptr("bedroom"), Labels: []string{"lighting"}},
+		},
+		Entities: []EntityRegistryEntry{
+			{EntityID: id("light.kitchen"), AreaID: ptr("kitchen")},
+			{EntityID: id("sensor.hub_temperature"), DeviceID: ptr("hub")},
+			{EntityID: id("sensor.hub_signal"), DeviceID: ptr("hub"), EntityCategory: ptr("diagnostic")},
+			{EntityID: id("switch.hub_moved"), DeviceID: ptr("hub"), AreaID: ptr("bedroom")},
+			{EntityID: id("light.bedside"), DeviceID: ptr("lamp")},
+			{EntityID: id("light.hidden"), AreaID: ptr("kitchen"), HiddenBy: &hidden},
+			{EntityID: id("fan.bedroom"), Labels: []string{"quiet"}},
+		},
+	}
+
+	tests := map[string]struct {
+		target   ServiceTarget
+		expected []string
+	}{
+		"Area": {
+			target:   ServiceTarget{AreaID: []string{"kitchen"}},
+			expected: []string{"light.kitchen", "sensor.hub_temperature"},
+		},
+		"Floor": {
+			target:   ServiceTarget{FloorID: []string{"upstairs"}},
+			expected: []string{"light.bedside", "switch.hub_moved"},
+		},
+		"Device": {
+			target:   ServiceTarget{DeviceID: []string{"hub"}},
+			expected: []string{"sensor.hub_temperature", "switch.hub_moved"},
+		},
+		"Label": {
+			target:   ServiceTarget{LabelID: []string{"quiet"}},
+			expected: []string{"fan.bedroom", "light.bedside", "switch.hub_moved"},
+		},
+		"Explicit entities are always included": {
+			target:   ServiceTarget{EntityID: entity.IDList{id("light.hidden"), id("light.unregistered")}},
+			expected: []string{"light.hidden", "light.unregistered"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var resolved []string
+			for _, id := range registries.Resolve(test.target) {
+				resolved = append(resolved, id.String())
+			}
+
+			assert.Equal(t, test.expected, resolved)
+		})
+	}
+}
+
+func TestRegistriesFilter(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	id := func(s string) entity.ID { return entity.MustParse(s) }
+
+	registries := Registries{
+		Floors: []Floor{{FloorID: "upstairs", Name: "Upstairs", Aliases: []string{"First floor"}}},
+		Labels: []Label{{LabelID: "night", Name: "Night"}},
+		Areas: []Area{
+			{AreaID: "bedroom", FloorID: ptr("upstairs")},
+			{AreaID: "kitchen"},
+		},
+		Devices: []Device{{ID: "lamp", AreaID: ptr("bedroom"), Labels: []string{"night"}}},
+		Entities: []EntityRegistryEntry{
+			{EntityID: id("light.bedside"), DeviceID: ptr("lamp")},
+			{EntityID: id("light.ceiling"), AreaID: ptr("bedroom")},
+			{EntityID: id("light.kitchen"), AreaID: ptr("kitchen"), Labels: []string{"night"}},
+			{EntityID: id("switch.lamp_power"), DeviceID: ptr("lamp"), AreaID: ptr("kitchen")},
+		},
+	}
+
+	floor, ok := registries.FloorByName("first floor")
+	assert.True(t, ok)
+
+	label, ok := registries.LabelByName("NIGHT")
+	assert.True(t, ok)
+
+	assert.Equal(t,
+		entity.IDList{id("light.bedside")},
+		registries.Filter(EntityFilter{Domain: "light", FloorID: floor.FloorID, LabelID: label.LabelID}),
+	)
+	assert.Equal(t,
+		entity.IDList{id("light.kitchen"), id("switch.lamp_power")},
+		registries.Filter(EntityFilter{AreaID: "kitchen"}),
+	)
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// ConfigValidation is the result of validating automation config. Sections
+	// that weren't validated are nil.
+	ConfigValidation struct {
+		Trigger   *SectionValidation `json:"trigger"`
+		Condition *SectionValidation `json:"condition"`
+		Action    *SectionValidation `json:"action"`
+	}
+
+	SectionValidation struct {
+		Valid bool    `json:"valid"`
+		Error *string `json:"error"`
+	}
+)
+
+// Valid reports whether every validated section is valid.
+func (v ConfigValidation) Valid() bool {
+	return v.Err() == nil
+}
+
+// Err returns the errors of every invalid section, or nil if all are valid.
+func (v ConfigValidation) Err() error {
+	var errs []error
+
+	for _, section := range []struct {
+		name   string
+		result *SectionValidation
+	}{
+		{"trigger", v.Trigger},
+		{"condition", v.Condition},
+		{"action", v.Action},
+	} {
+		if section.result == nil || section.result.Valid {
+			continue
+		}
+
+		msg := "invalid"
+		if section.result.Error != nil {
+			msg = *section.result.Error
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %s", section.name, msg))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"strconv"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+)
+
+type (
+	// SensorState is a sensor entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/sensor
+	SensorState struct {
+		Entity
+		SensorAttributes
+	}
+
+	SensorAttributes struct {
+		CommonAttributes
+		StateClass        *SensorStateClass `json:"state_class"`
+		UnitOfMeasurement *string           `json:"unit_of_measurement"`
+		Options           []string          `json:"options"` // Possible states of enum sensors
+	}
+
+	SensorStateClass string
+)
+
+const (
+	SensorStateClassMeasurement     SensorStateClass = "measurement"
+	SensorStateClassTotal           SensorStateClass = "total"
+	SensorStateClassTotalIncreasing SensorStateClass = "total_increasing"
+)
+
+// NewSensorState parses the attributes of a sensor entity.
+func NewSensorState(e Entity) (SensorState, error) {
+	s := SensorState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Sensor, &s.SensorAttributes)
+}
+
+// Float parses the state of a numeric sensor.
+func (s SensorState) Float() (float64, error) {
+	return strconv.ParseFloat(s.State.String(), 64)
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// StatisticPeriod is the length of the periods statistics are grouped in.
+	StatisticPeriod string
+
+	// StatisticType is a value kept for each period. Measurements have a mean,
+	// min and max, while meters have a state, sum and change.
+	StatisticType string
+
+	// StatisticMeanType is how the mean of a statistic is calculated. Newer
+	// Home Assistant versions send it in place of has_mean.
+	StatisticMeanType int
+
+	// UnixMillis is a timestamp sent as milliseconds since the Unix epoch.
+	UnixMillis float64
+
+	// Statistic is the value of a statistic over a period. Values not requested
+	// or not kept for the statistic are nil.
+	Statistic struct {
+		Start     UnixMillis  `json:"start"`
+		End       UnixMillis  `json:"end"`
+		Mean      *float64    `json:"mean"`
+		Min       *float64    `json:"min"`
+		Max       *float64    `json:"max"`
+		State     *float64    `json:"state"`
+		Sum       *float64    `json:"sum"`
+		Change    *float64    `json:"change"`
+		LastReset *UnixMillis `json:"last_reset"`
+	}
+
+	// StatisticMetadata describes a statistic kept by the recorder.
+	StatisticMetadata struct {
+		StatisticID                 string             `json:"statistic_id"`
+		Source                      string             `json:"source"`
+		Name                        *string            `json:"name"`
+		HasMean                     bool               `json:"has_mean"`
+		HasSum                      bool               `json:"has_sum"`
+		StatisticsUnitOfMeasurement *string            `json:"statistics_unit_of_measurement"` // Unit the values are stored in
+		DisplayUnitOfMeasurement    *string            `json:"display_unit_of_measurement"`
+		UnitClass                   *string            `json:"unit_class"` // Unit classes can be converted, such as energy or temperature
+		MeanType                    *StatisticMeanType `json:"mean_type"`  // Nil on versions that only send has_mean
+	}
+)
+
+const (
+	StatisticPeriod5Minute StatisticPeriod = "5minute"
+	StatisticPeriodHour    StatisticPeriod = "hour"
+	StatisticPeriodDay     StatisticPeriod = "day"
+	StatisticPeriodWeek    StatisticPeriod = "week"
+	StatisticPeriodMonth   StatisticPeriod = "month"
+)
+
+const (
+	StatisticTypeMean      StatisticType = "mean"
+	StatisticTypeMin       StatisticType = "min"
+	StatisticTypeMax       StatisticType = "max"
+	StatisticTypeState     StatisticType = "state"
+	StatisticTypeSum       StatisticType = "sum"
+	StatisticTypeChange    StatisticType = "change"
+	StatisticTypeLastReset StatisticType = "last_reset"
+)
+
+const (
+	StatisticMeanTypeNone       StatisticMeanType = 0
+	StatisticMeanTypeArithmetic StatisticMeanType = 1
+	StatisticMeanTypeCircular   StatisticMeanType = 2 // For angles, such as wind direction
+)
+
+// Time converts the timestamp to a time.Time.
+func (t UnixMillis) Time() time.Time {
+	return time.UnixMicro(int64(math.Round(float64(t) * 1e3)))
+}
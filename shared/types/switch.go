@@ -0,0 +1,44 @@
+package types
+
+import "github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+
+type (
+	// SwitchState is a switch entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/switch
+	SwitchState struct {
+		Entity
+		CommonAttributes
+	}
+
+	// BinarySensorState is a binary sensor entity with its attributes parsed.
+	// https://www.home-assistant.io/integrations/binary_sensor
+	BinarySensorState struct {
+		Entity
+		CommonAttributes
+	}
+)
+
+// NewSwitchState parses the attributes of a switch entity.
+func NewSwitchState(e Entity) (SwitchState, error) {
+	s := SwitchState{Entity: e}
+
+	return s, decodeDomainState(e, domains.Switch, &s.CommonAttributes)
+}
+
+// IsOn reports whether the switch is on.
+func (s SwitchState) IsOn() bool {
+	return s.State == "on"
+}
+
+// NewBinarySensorState parses the attributes of a binary sensor entity.
+func NewBinarySensorState(e Entity) (BinarySensorState, error) {
+	s := BinarySensorState{Entity: e}
+
+	return s, decodeDomainState(e, domains.BinarySensor, &s.CommonAttributes)
+}
+
+// IsOn reports whether the binary sensor is on. What on means depends on the
+// device class, for example open for a door or detected for motion.
+func (s BinarySensorState) IsOn() bool {
+	return s.State == "on"
+}
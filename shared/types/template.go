@@ -0,0 +1,34 @@
+package types
+
+import "encoding/json"
+
+type (
+	// TemplateRender is sent by a template subscription each time the
+	// template is rendered. If the render failed and errors are reported,
+	// Error and Level are set instead of Result.
+	TemplateRender struct {
+		Result    json.RawMessage   `json:"result"` // Home Assistant parses results into JSON types where possible
+		Listeners TemplateListeners `json:"listeners"`
+		Error     string            `json:"error"`
+		Level     string            `json:"level"` // ERROR or WARNING
+	}
+
+	// TemplateListeners is what Home Assistant tracks to know when to render
+	// the template again.
+	TemplateListeners struct {
+		All      bool     `json:"all"` // Any state change
+		Entities []string `json:"entities"`
+		Domains  []string `json:"domains"`
+		Time     bool     `json:"time"` // Rendered every minute as the template uses now()
+	}
+)
+
+// String returns the result as text, unquoting string results.
+func (t TemplateRender) String() string {
+	var s string
+	if err := json.Unmarshal(t.Result, &s); err == nil {
+		return s
+	}
+
+	return string(t.Result)
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/platforms"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+// TriggerData is implemented by the typed trigger variables returned by Trigger.Decode.
+type TriggerData interface {
+	Base() TriggerBase
+}
+
+type (
+	// TriggerBase holds the variables shared by every trigger platform.
+	TriggerBase struct {
+		ID          string             `json:"id"`
+		Idx         string             `json:"idx"`
+		Alias       *string            `json:"alias"`
+		Platform    platforms.Platform `json:"platform"`
+		Description string             `json:"description"`
+	}
+
+	StateTriggerData struct {
+		TriggerBase
+		EntityID  entity.ID       `json:"entity_id"`
+		FromState *Entity         `json:"from_state"`
+		ToState   *Entity         `json:"to_state"`
+		Attribute *string         `json:"attribute"`
+		For       json.RawMessage `json:"for"`
+	}
+
+	NumericStateTriggerData struct {
+		TriggerBase
+		EntityID  entity.ID       `json:"entity_id"`
+		FromState *Entity         `json:"from_state"`
+		ToState   *Entity         `json:"to_state"`
+		Attribute *string         `json:"attribute"`
+		Above     *float64        `json:"above"`
+		Below     *float64        `json:"below"`
+		For       json.RawMessage `json:"for"`
+	}
+
+	TimeTriggerData struct {
+		TriggerBase
+		Now      time.Time  `json:"now"`
+		EntityID *entity.ID `json:"entity_id"`
+	}
+
+	TimePatternTriggerData struct {
+		TriggerBase
+		Now time.Time `json:"now"`
+	}
+
+	SunTriggerData struct {
+		TriggerBase
+		Event  string          `json:"event"`
+		Offset json.RawMessage `json:"offset"`
+	}
+
+	ZoneTriggerData struct {
+		TriggerBase
+		EntityID  entity.ID `json:"entity_id"`
+		FromState *Entity   `json:"from_state"`
+		ToState   *Entity   `json:"to_state"`
+		Zone      *Entity   `json:"zone"`
+		Event     string    `json:"event"`
+	}
+
+	TemplateTriggerData struct {
+		TriggerBase
+		EntityID  *entity.ID      `json:"entity_id"`
+		FromState *Entity         `json:"from_state"`
+		ToState   *Entity         `json:"to_state"`
+		For       json.RawMessage `json:"for"`
+	}
+
+	EventTriggerData struct {
+		TriggerBase
+		Event Event `json:"event"`
+	}
+
+	MQTTTriggerData struct {
+		TriggerBase
+		Topic       string          `json:"topic"`
+		Payload     string          `json:"payload"`
+		PayloadJSON json.RawMessage `json:"payload_json"`
+		QoS         int             `json:"qos"`
+	}
+
+	WebhookTriggerData struct {
+		TriggerBase
+		WebhookID string              `json:"webhook_id"`
+		JSON      json.RawMessage     `json:"json"`
+		Data      map[string][]string `json:"data"`
+		Query     map[string][]string `json:"query"`
+	}
+
+	HomeAssistantTriggerData struct {
+		TriggerBase
+		Event string `json:"event"`
+	}
+
+	// UnknownTriggerData is returned for platforms without a typed structure.
+	UnknownTriggerData struct {
+		TriggerBase
+		Raw json.RawMessage `json:"-"`
+	}
+)
+
+func (t TriggerBase) Base() TriggerBase {
+	return t
+}
+
+// Decode parses the trigger variables into the typed structure matching their platform.
+func (t Trigger) Decode() (TriggerData, error) {
+	var base TriggerBase
+	if err := t.UnmarshalTrigger(&base); err != nil {
+		return nil, fmt.Errorf("failed to decode trigger: %w", err)
+	}
+
+	var data TriggerData
+
+	switch base.Platform {
+	case platforms.State:
+		data = &StateTriggerData{}
+	case platforms.NumericState:
+		data = &NumericStateTriggerData{}
+	case platforms.Time:
+		data = &TimeTriggerData{}
+	case platforms.TimePattern:
+		data = &TimePatternTriggerData{}
+	case platforms.Sun:
+		data = &SunTriggerData{}
+	case platforms.Zone:
+		data = &ZoneTriggerData{}
+	case platforms.Template:
+		data = &TemplateTriggerData{}
+	case platforms.Event:
+		data = &EventTriggerData{}
+	case platforms.MQTT:
+		data = &MQTTTriggerData{}
+	case platforms.Webhook:
+		data = &WebhookTriggerData{}
+	case platforms.HomeAssistant:
+		data = &HomeAssistantTriggerData{}
+	default:
+		return &UnknownTriggerData{TriggerBase: base, Raw: t.Trigger}, nil
+	}
+
+	if err := t.UnmarshalTrigger(data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s trigger: %w", base.Platform, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriggerDecode(t *testing.T) {
+	t.Run("State", func(t *testing.T) {
+		var trigger Trigger
+		err := json.Unmarshal([]byte(`{"trigger": {
+			"id": "0", "idx": "0", "alias": null, "platform": "state",
+			"entity_id": "light.kitchen",
+			"from_state": {"entity_id": "light.kitchen", "state": "off", "attributes": {}},
+			"to_state": {"entity_id": "light.kitchen", "state": "on", "attributes": {"brightness": 255}},
+			"for": null, "attribute": null, "description": "state of light.kitchen"
+		}}`), &trigger)
+		assert.NoError(t, err)
+
+		data, err := trigger.Decode()
+		assert.NoError(t, err)
+
+		stateTrigger, ok := data.(*StateTriggerData)
+		assert.True(t, ok)
+		assert.Equal(t, "light.kitchen", stateTrigger.EntityID.String())
+		assert.Equal(t, state.Value("off"), stateTrigger.FromState.State)
+		assert.Equal(t, state.Value("on"), stateTrigger.ToState.State)
+		assert.Equal(t, "state of light.kitchen", data.Base().Description)
+	})
+
+	t.Run("Event", func(t *testing.T) {
+		trigger := Trigger{Trigger: json.RawMessage(`{
+			"id": "0", "idx": "0", "platform": "event",
+			"event": {"event_type": "my_event", "data": {"foo": "bar"}, "origin": "LOCAL"}
+		}`)}
+
+		data, err := trigger.Decode()
+		assert.NoError(t, err)
+
+		eventTrigger, ok := data.(*EventTriggerData)
+		assert.True(t, ok)
+		assert.Equal(t, "my_event", eventTrigger.Event.EventType)
+		assert.JSONEq(t, `{"foo": "bar"}`, string(eventTrigger.Event.Data))
+	})
+
+	t.Run("Unknown Platform", func(t *testing.T) {
+		trigger := Trigger{Trigger: json.RawMessage(`{"platform": "calendar", "event": "start"}`)}
+
+		data, err := trigger.Decode()
+		assert.NoError(t, err)
+
+		unknown, ok := data.(*UnknownTriggerData)
+		assert.True(t, ok)
+		assert.Equal(t, "calendar", unknown.Platform.String())
+	})
+}
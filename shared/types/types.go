@@ -2,10 +2,12 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/config"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/version"
@@ -138,6 +140,15 @@ type (
 		FloorID  []string      `json:"floor_id,omitempty"`
 		LabelID  []string      `json:"label_id,omitempty"`
 	}
+
+	// CallServiceParams describes a service call. It is accepted by both the
+	// REST and websocket clients.
+	CallServiceParams struct {
+		Domain      domains.Domain
+		Service     string
+		ServiceData any
+		Target      ServiceTarget
+	}
 )
 
 type (
@@ -175,6 +186,35 @@ func (s Entity) UnmarshalAttributes(v any) error {
 	return json.Unmarshal(s.Attributes, v)
 }
 
+// UnmarshalTrigger parses the trigger variables into the provided structure.
+func (t Trigger) UnmarshalTrigger(v any) error {
+	if t.Trigger == nil {
+		return fmt.Errorf("trigger is nil")
+	}
+
+	return json.Unmarshal(t.Trigger, v)
+}
+
+var (
+	ErrServiceNotFound   = errors.New("service not found")
+	ErrServiceNoResponse = errors.New("service does not return a response")
+)
+
+// CheckResponse returns an error unless domain.service exists and can return
+// a response.
+func (s Services) CheckResponse(domain domains.Domain, service string) error {
+	svc, exists := s[string(domain)][service]
+	if !exists {
+		return fmt.Errorf("%w: %s.%s", ErrServiceNotFound, domain, service)
+	}
+
+	if svc.Response == nil {
+		return fmt.Errorf("%w: %s.%s", ErrServiceNoResponse, domain, service)
+	}
+
+	return nil
+}
+
 func (e Entities) SortStates() EntitiesMap {
 	s := make(map[entity.ID]Entity, len(e))
 
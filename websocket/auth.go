@@ -3,6 +3,7 @@
 package websocket
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -22,18 +23,25 @@ type (
 	}
 )
 
-// Handle authenticating websocket on initial run or reconnect
-func (c *Client) authenticate() error {
+// Handle authenticating websocket on initial run or reconnect. The whole
+// handshake has to finish within the client timeout.
+func (c *Client) authenticate(ctx context.Context, conn *connection) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
 	var resp authResponse
-	if err := c.wsConn.ReadJSON(&resp); err != nil {
-		c.logger.Error("%s: %w", *resp.Message, err)
+	if err := conn.readJSON(ctx, &resp); err != nil {
+		c.logger.Error("error reading auth required message: %w", err)
 		return err
 	}
 
+	c.mu.Lock()
 	c.haVersion = resp.Version
-	c.logger.Debug("version: %s", c.haVersion.String())
+	c.mu.Unlock()
+
+	c.logger.Debug("version: %s", resp.Version.String())
 
-	if !c.haVersion.Minimum(2024, 1) {
+	if !resp.Version.Minimum(2024, 1) {
 		return ErrNotMinimumVersion
 	}
 
@@ -42,17 +50,21 @@ func (c *Client) authenticate() error {
 			Type:        messageTypeAuth,
 			AccessToken: c.accessToken,
 		}
-		if err := c.wsConn.WriteJSON(request); err != nil {
+		if err := conn.writeJSON(ctx, request); err != nil {
 			c.logger.Error("error sending auth message. attempt %d: %w", i+1, err)
-			time.Sleep(2 * time.Second)
+			if err := pause(ctx, 2*time.Second); err != nil {
+				return err
+			}
 
 			continue
 		}
 
 		var resp authResponse
-		if err := c.wsConn.ReadJSON(&resp); err != nil {
+		if err := conn.readJSON(ctx, &resp); err != nil {
 			c.logger.Error("error reading auth message. attempt %d: %w", i+1, err)
-			time.Sleep(2 * time.Second)
+			if err := pause(ctx, 2*time.Second); err != nil {
+				return err
+			}
 
 			continue
 		}
@@ -65,7 +77,9 @@ func (c *Client) authenticate() error {
 			return errors.New(*resp.Message)
 		default:
 			c.logger.Error("%s. attempt %d", resp.Type.String(), i+1)
-			time.Sleep(2 * time.Second)
+			if err := pause(ctx, 2*time.Second); err != nil {
+				return err
+			}
 
 			continue
 		}
@@ -73,3 +87,16 @@ func (c *Client) authenticate() error {
 
 	return fmt.Errorf("failed to authenticate")
 }
+
+// Wait d before the next attempt, or return ctx.Err() if ctx is done first.
+func pause(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
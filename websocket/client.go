@@ -1,13 +1,22 @@
+// A Go client for communicating with Home Assistant's WebSocket API.
+// https://developers.home-assistant.io/docs/api/websocket
+//
+// Commands that predate context support come in pairs, such as CallService
+// and CallServiceContext, where the first uses the client timeout. Commands
+// added since take a context.Context as their first argument and have no
+// variant without one. Without a deadline on ctx the client timeout applies.
+
 package websocket
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/url"
 	"regexp"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/ryanjohnsontv/go-homeassistant/logging"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
@@ -19,35 +28,59 @@ type Client struct {
 	accessToken             string // Long-Lived Token from Home Assistant
 	secure                  bool
 	haVersion               version.Version
-	wsConn                  *websocket.Conn
+	conn                    *connection
+	dialer                  Dialer
+	recorder                *recorder
 	timeout                 time.Duration
 	logger                  logging.Logger
 	msgID                   int64
+	listenerID              int64
 	eventHandler            map[int64]eventHandler
-	triggerHandler          map[int64][]triggerHandler
+	triggerHandler          map[int64]triggerHandler
 	entityListeners         map[entity.ID][]entityListener
 	regexEntityListeners    map[*regexp.Regexp][]entityListener
 	dateTimeEntityListeners map[time.Time]map[entity.ID][]dateTimeEntityTrigger
 	resultChan              map[int64]chan []byte
 	pongChan                chan bool
-	stopChan                chan bool
-	reconnectChan           chan bool
-	mu                      sync.Mutex
-	msgHistory              map[int64]cmdMessage
-	EntitiesMap             types.EntitiesMap
+	backoff                 Backoff
+	lost                    chan lostConnection // Sent to by listen, see supervise
+	done                    chan struct{}       // Closed by Close
+	stateHandler            func(ConnectionState, error)
+	stateQueue              serialQueue
+	inflight                sync.WaitGroup // Commands being sent, see Shutdown
+	routines                sync.WaitGroup // Connection goroutines
+	supervising             sync.WaitGroup
+	callbacks               callbackGroup
+	mu                      sync.RWMutex // Guards every map below as well as conn, state, closed and initialized
+	initialized             bool         // Set after the first successful run, later runs are reconnects
+	closed                  bool
+	state                   ConnectionState
+	services                types.Services    // Cached to check which services return a response
+	registryCache           *registryCache    // Set by WithRegistryCache
+	compressed              *compressedStates // Set by WithCompressedStates
+	lifecycle               *lifecycle
+	// EntitiesMap is kept current while connected. Use Entities or Entity
+	// instead of reading it directly from other goroutines.
+	EntitiesMap types.EntitiesMap
 }
 
 type (
 	ClientOption func(*Client)
 
 	eventHandler struct {
-		EventType string
-		Callback  func(types.Event)
+		EventType    string
+		Callback     func(types.Event)
+		request      cmdMessage            // Replayed to resubscribe after a reconnect
+		updatesState bool                  // Internal state_changed subscription that keeps EntitiesMap current
+		compressed   bool                  // Internal subscribe_entities subscription, see WithCompressedStates
+		raw          func(json.RawMessage) // Called instead of Callback for events that aren't a types.Event, must not block
 	}
 	triggerHandler struct {
 		Callback func(types.Trigger)
+		request  cmdMessage // Replayed to resubscribe after a reconnect
 	}
 	entityListener struct {
+		id            int64
 		callback      func(*types.StateChange)
 		FilterOptions filterOptions
 	}
@@ -67,20 +100,24 @@ func NewClient(host, accessToken string, options ...ClientOption) (*Client, erro
 	c := &Client{
 		accessToken:             accessToken,
 		timeout:                 10 * time.Second,
+		dialer:                  dialWebsocket,
 		logger:                  &logging.DefaultLogger{},
 		eventHandler:            make(map[int64]eventHandler),
-		triggerHandler:          make(map[int64][]triggerHandler),
+		triggerHandler:          make(map[int64]triggerHandler),
 		entityListeners:         make(map[entity.ID][]entityListener),
 		regexEntityListeners:    make(map[*regexp.Regexp][]entityListener),
 		dateTimeEntityListeners: make(map[time.Time]map[entity.ID][]dateTimeEntityTrigger),
 		resultChan:              make(map[int64]chan []byte),
-		pongChan:                make(chan bool),
-		stopChan:                make(chan bool),
-		reconnectChan:           make(chan bool),
+		pongChan:                make(chan bool, 1),
+		backoff:                 DefaultBackoff,
+		lost:                    make(chan lostConnection),
+		done:                    make(chan struct{}),
 		EntitiesMap:             make(types.EntitiesMap),
-		msgHistory:              make(map[int64]cmdMessage),
+		lifecycle:               newLifecycle(),
 	}
 
+	c.stateQueue.group = &c.callbacks
+
 	for _, option := range options {
 		option(c)
 	}
@@ -91,7 +128,19 @@ func NewClient(host, accessToken string, options ...ClientOption) (*Client, erro
 
 	c.wsURL = wsURL.String()
 
-	return c, c.run()
+	if err := c.run(); err != nil {
+		c.setConnectionState(StateDisconnected, err)
+		return c, err
+	}
+
+	c.supervising.Add(1)
+
+	go func() {
+		defer c.supervising.Done()
+		c.supervise()
+	}()
+
+	return c, nil
 }
 
 func WithCustomLogger(logger logging.Logger) ClientOption {
@@ -106,27 +155,148 @@ func WithSecureConnection() ClientOption {
 	}
 }
 
+// WithDialer replaces the transport used to connect to Home Assistant, for
+// example with the Dial method of a Replay from NewReplay.
+func WithDialer(dialer Dialer) ClientOption {
+	return func(c *Client) {
+		c.dialer = dialer
+	}
+}
+
+// Connect and authenticate, then sync states and subscriptions. The
+// connection is closed again if anything fails, and attempts in progress are
+// abandoned once the client is closed.
 func (c *Client) run() error {
-	if err := c.connect(); err != nil {
+	ctx, cancel := c.untilClosed()
+	defer cancel()
+
+	conn, err := c.connect(ctx)
+	if err != nil {
 		return err
 	}
 
-	go c.listen()
-	go c.startHeartbeat()
+	c.goRoutine(func() { c.listen(conn) })
+	c.goRoutine(func() { c.startHeartbeat(conn) })
 
-	_, err := c.GetStates()
-	if err != nil {
+	if err := c.setup(ctx); err != nil {
+		conn.close()
 		return err
 	}
 
-	err = c.SubscribeToEvent("state_changed", nil)
-	if err != nil {
+	c.setConnectionState(StateConnected, nil)
+
+	return nil
+}
+
+// Prepare a new connection. On reconnect, subscriptions are replayed and
+// states resynced.
+func (c *Client) setup(ctx context.Context) error {
+	c.mu.RLock()
+	reconnect := c.initialized
+	c.mu.RUnlock()
+
+	if reconnect {
+		if c.compressed != nil {
+			c.dropCompressedSubscriptions()
+		}
+
+		c.resubscribe(ctx)
+
+		c.lifecycle.mu.Lock()
+		tracking := c.lifecycle.tracking
+		c.lifecycle.mu.Unlock()
+
+		// Before resyncing, so changes made while Home Assistant restarted are held back
+		if tracking {
+			if err := c.refreshLifecycle(ctx); err != nil {
+				return err
+			}
+		}
+
+		if c.registryCache != nil {
+			if err := c.loadRegistries(ctx); err != nil {
+				c.logger.Error("failed to reload registries: %w", err)
+			}
+		}
+
+		if c.compressed != nil {
+			return c.syncStates(ctx)
+		}
+
+		return c.resyncStates(ctx)
+	}
+
+	if c.lifecycle.wait {
+		if err := c.trackLifecycle(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := c.syncStates(ctx); err != nil {
 		return err
 	}
 
+	if c.registryCache != nil {
+		if err := c.startRegistryCache(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.initialized = true
+	c.mu.Unlock()
+
 	return nil
 }
 
-func (c *Client) Close() {
-	c.wsConn.Close()
+// Load the current states and subscribe to keep EntitiesMap current.
+func (c *Client) syncStates(ctx context.Context) error {
+	if c.compressed != nil && c.compressed.filtered {
+		return c.updateFilteredStates(ctx)
+	}
+
+	if c.compressed != nil {
+		c.compressed.subMu.Lock()
+		defer c.compressed.subMu.Unlock()
+
+		return c.subscribeEntities(ctx, c.compressed.entityIDs)
+	}
+
+	if _, err := c.GetStatesContext(ctx); err != nil {
+		return err
+	}
+
+	request := subscribeToEventRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeSubscribeEvent,
+		},
+		EventType: "state_changed",
+	}
+
+	return c.subscribeToEvent(ctx, &request, eventHandler{EventType: "state_changed", updatesState: true})
+}
+
+// Entities returns a copy of the current entity states.
+func (c *Client) Entities() types.EntitiesMap {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entities := make(types.EntitiesMap, len(c.EntitiesMap))
+	for id, e := range c.EntitiesMap {
+		entities[id] = e
+	}
+
+	return entities
+}
+
+// Entity returns the current state of an entity.
+func (c *Client) Entity(id entity.ID) (types.Entity, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.EntitiesMap.Exists(id); err != nil {
+		return types.Entity{}, err
+	}
+
+	return c.EntitiesMap[id], nil
 }
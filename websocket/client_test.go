@@ -0,0 +1,1382 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/hatest"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/config"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/trigger"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+func mustParse(t *testing.T, entityID string) entity.ID {
+	t.Helper()
+
+	id, err := entity.Parse(entityID)
+	require.NoError(t, err)
+
+	return id
+}
+
+func newTestClient(t *testing.T, server *hatest.Server, options ...ClientOption) *Client {
+	t.Helper()
+
+	options = append([]ClientOption{WithCustomLogger(nopLogger{})}, options...)
+
+	client, err := NewClient(server.Host(), server.Token, options...)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	server := hatest.NewServer(t, hatest.WithStates(types.Entity{EntityID: kitchen, State: "on"}))
+
+	client := newTestClient(t, server)
+
+	e, err := client.Entity(kitchen)
+	assert.NoError(t, err)
+	assert.Equal(t, state.Value("on"), e.State)
+	assert.Len(t, server.Messages("subscribe_events"), 1)
+}
+
+func TestNewClientInvalidToken(t *testing.T) {
+	server := hatest.NewServer(t)
+
+	_, err := NewClient(server.Host(), "wrong-token", WithCustomLogger(nopLogger{}))
+	assert.Error(t, err)
+}
+
+func TestConcurrentCommandsAndStateUpdates(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	server := hatest.NewServer(t, hatest.WithStates(types.Entity{EntityID: kitchen, State: "off"}))
+	client := newTestClient(t, server)
+
+	var (
+		wg        sync.WaitGroup
+		callbacks atomic.Int64
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := client.CallService(CallServiceParams{Domain: "light", Service: "toggle"})
+			assert.NoError(t, err)
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			_, err := client.GetConfig()
+			assert.NoError(t, err)
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			sub, err := client.AddEntityListener(kitchen, func(*types.StateChange) {
+				callbacks.Add(1)
+			})
+			if assert.NoError(t, err) {
+				_ = client.Entities()
+				assert.NoError(t, sub.Unsubscribe())
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		server.SetState("light.kitchen", "on", nil)
+	}
+
+	server.SetState("light.kitchen", "off", nil)
+	wg.Wait()
+
+	assert.Len(t, server.ServiceCalls(), 20)
+	assert.Eventually(t, func() bool {
+		e, err := client.Entity(kitchen)
+		return err == nil && e.State == "off"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWriteContextCancelled(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+
+	server.Handle("call_service", func(*hatest.Conn, hatest.Message) (any, error) {
+		return nil, hatest.ErrNoResult
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.CallServiceContext(ctx, CallServiceParams{Domain: "light", Service: "toggle"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	client.mu.RLock()
+	assert.Empty(t, client.resultChan)
+	client.mu.RUnlock()
+}
+
+func TestWriteContextDeadline(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server, func(c *Client) {
+		c.timeout = 50 * time.Millisecond
+	})
+
+	server.Handle("get_panels", func(*hatest.Conn, hatest.Message) (any, error) {
+		time.Sleep(200 * time.Millisecond)
+		return types.Panels{}, nil
+	})
+
+	// A deadline longer than the client timeout is honoured
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := client.GetPanelsContext(ctx)
+	require.NoError(t, err)
+
+	// Without one the client timeout applies
+	_, err = client.GetPanels()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSubscribeToEvent(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+
+	events := make(chan types.Event, 1)
+	sub, err := client.SubscribeToEvent("custom_event", func(e types.Event) {
+		events <- e
+	})
+	require.NoError(t, err)
+
+	server.FireEvent("custom_event", map[string]any{"foo": "bar"})
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "custom_event", e.EventType)
+		assert.JSONEq(t, `{"foo":"bar"}`, string(e.Data))
+	case <-time.After(time.Second):
+		t.Fatal("event callback was not called")
+	}
+
+	require.NoError(t, sub.Unsubscribe())
+
+	unsubscribes := server.Messages("unsubscribe_events")
+	require.Len(t, unsubscribes, 1)
+
+	var unsubscribe struct {
+		Subscription int64 `json:"subscription"`
+	}
+
+	require.NoError(t, unsubscribes[0].Decode(&unsubscribe))
+
+	subscribes := server.Messages("subscribe_events")
+	assert.Equal(t, subscribes[len(subscribes)-1].ID, unsubscribe.Subscription)
+}
+
+func TestSubscribeToTrigger(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+
+	triggers := make(chan types.Trigger, 1)
+	_, err := client.SubscribeToTrigger(map[string]any{"platform": "state"}, func(trigger types.Trigger) {
+		triggers <- trigger
+	})
+	require.NoError(t, err)
+
+	server.FireTrigger(map[string]any{"platform": "state", "entity_id": "light.kitchen"})
+
+	select {
+	case trigger := <-triggers:
+		data, err := trigger.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, "light.kitchen", data.(*types.StateTriggerData).EntityID.String())
+	case <-time.After(time.Second):
+		t.Fatal("trigger callback was not called")
+	}
+}
+
+func TestCallService(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+
+	ctx, err := client.CallService(CallServiceParams{
+		Domain:      "light",
+		Service:     "turn_on",
+		ServiceData: map[string]any{"brightness": 255},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, ctx.ID)
+
+	calls := server.ServiceCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, float64(255), calls[0].ServiceData["brightness"])
+}
+
+func TestCallServiceWithResponse(t *testing.T) {
+	server := hatest.NewServer(t, hatest.WithServices(types.Services{
+		"weather": {"get_forecasts": {Response: &types.ServiceResponse{}}},
+		"light":   {"turn_on": {}},
+	}))
+	server.HandleService("weather", "get_forecasts", func(hatest.ServiceCall) (any, error) {
+		return map[string]any{"weather.home": map[string]any{"forecast": []any{map[string]any{"temperature": 21.5}}}}, nil
+	})
+
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	var forecasts map[string]struct {
+		Forecast []struct {
+			Temperature float64 `json:"temperature"`
+		} `json:"forecast"`
+	}
+
+	_, err := client.CallServiceWithResponse(ctx, CallServiceParams{
+		Domain:      "weather",
+		Service:     "get_forecasts",
+		ServiceData: map[string]any{"type": "daily"},
+	}, &forecasts)
+	require.NoError(t, err)
+	assert.InDelta(t, 21.5, forecasts["weather.home"].Forecast[0].Temperature, 0)
+
+	calls := server.ServiceCalls()
+	require.Len(t, calls, 1)
+	assert.True(t, calls[0].ReturnResponse)
+
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "light", Service: "turn_on"}, nil)
+	assert.ErrorIs(t, err, types.ErrServiceNoResponse)
+
+	_, err = client.CallServiceWithResponse(ctx, CallServiceParams{Domain: "todo", Service: "get_items"}, nil)
+	assert.ErrorIs(t, err, types.ErrServiceNotFound)
+	assert.Len(t, server.ServiceCalls(), 1)
+}
+
+func TestEntityRegistry(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	area := "living_room"
+	server := hatest.NewServer(t, hatest.WithEntityRegistry(types.EntityRegistryEntryExtended{
+		EntityRegistryEntry: types.EntityRegistryEntry{
+			ID:       "1",
+			EntityID: kitchen,
+			UniqueID: "abc",
+			Platform: "hue",
+			AreaID:   &area,
+		},
+	}))
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	entries, err := client.ListEntityRegistry(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "hue", entries[0].Platform)
+
+	name := "Kitchen Ceiling"
+	hidden := types.HiddenByUser
+	noArea := ""
+
+	result, err := client.UpdateEntityRegistryEntry(ctx, kitchen, EntityRegistryUpdate{
+		Name:     &name,
+		HiddenBy: &hidden,
+		AreaID:   &noArea,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, name, *result.EntityEntry.Name)
+	assert.Equal(t, types.HiddenByUser, *result.EntityEntry.HiddenBy)
+	assert.Nil(t, result.EntityEntry.AreaID)
+
+	updates := server.Messages("config/entity_registry/update")
+	require.Len(t, updates, 1)
+	assert.JSONEq(t, `{"id": `+fmt.Sprint(updates[0].ID)+`, "type": "config/entity_registry/update",
+		"entity_id": "light.kitchen", "name": "Kitchen Ceiling", "hidden_by": "user", "area_id": null}`, string(updates[0].Raw))
+
+	entry, err := client.GetEntityRegistryEntry(ctx, kitchen)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", entry.UniqueID)
+
+	require.NoError(t, client.RemoveEntityRegistryEntry(ctx, kitchen))
+
+	_, err = client.GetEntityRegistryEntry(ctx, kitchen)
+	assert.Error(t, err)
+}
+
+func TestRegistries(t *testing.T) {
+	hubArea := "kitchen"
+	hub := "hub"
+	server := hatest.NewServer(t,
+		hatest.WithAreas(types.Area{AreaID: "kitchen", Name: "Kitchen"}),
+		hatest.WithDevices(types.Device{ID: hub, AreaID: &hubArea, ConfigEntries: []string{"entry"}}),
+		hatest.WithEntityRegistry(types.EntityRegistryEntryExtended{
+			EntityRegistryEntry: types.EntityRegistryEntry{EntityID: mustParse(t, "sensor.hub_temperature"), DeviceID: &hub},
+		}),
+	)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	level := 1
+	floor, err := client.CreateFloor(ctx, FloorParams{Name: "Upstairs", Level: &level})
+	require.NoError(t, err)
+	assert.Equal(t, "upstairs", floor.FloorID)
+	assert.Equal(t, &level, floor.Level)
+
+	floor, err = client.UpdateFloor(ctx, floor.FloorID, FloorParams{ClearLevel: true})
+	require.NoError(t, err)
+	assert.Nil(t, floor.Level)
+	assert.Equal(t, "Upstairs", floor.Name)
+
+	bedroom, err := client.CreateArea(ctx, AreaParams{Name: "Bedroom", FloorID: &floor.FloorID})
+	require.NoError(t, err)
+	assert.Equal(t, "bedroom", bedroom.AreaID)
+
+	label, err := client.CreateLabel(ctx, LabelParams{Name: "Quiet"})
+	require.NoError(t, err)
+
+	bedroom, err = client.UpdateArea(ctx, bedroom.AreaID, AreaParams{Labels: []string{label.LabelID}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quiet"}, bedroom.Labels)
+	assert.Equal(t, "Bedroom", bedroom.Name)
+
+	areas, err := client.ListAreas(ctx)
+	require.NoError(t, err)
+	assert.Len(t, areas, 2)
+
+	device, err := client.UpdateDevice(ctx, hub, DeviceRegistryUpdate{AreaID: &bedroom.AreaID})
+	require.NoError(t, err)
+	assert.Equal(t, "bedroom", *device.AreaID)
+
+	ids, err := client.ResolveTarget(ctx, types.ServiceTarget{FloorID: []string{"upstairs"}})
+	require.NoError(t, err)
+	assert.Equal(t, entity.IDList{mustParse(t, "sensor.hub_temperature")}, ids)
+
+	require.NoError(t, client.DeleteArea(ctx, "kitchen"))
+	assert.Error(t, client.DeleteArea(ctx, "kitchen"))
+
+	require.NoError(t, client.RemoveDeviceConfigEntry(ctx, hub, "entry"))
+
+	devices, err := client.ListDevices(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+}
+
+func TestRegistryCache(t *testing.T) {
+	upstairs := "upstairs"
+	bedroom := "bedroom"
+	server := hatest.NewServer(t,
+		hatest.WithFloors(types.Floor{FloorID: upstairs, Name: "Upstairs"}),
+		hatest.WithAreas(types.Area{AreaID: bedroom, Name: "Bedroom", FloorID: &upstairs}),
+		hatest.WithEntityRegistry(
+			types.EntityRegistryEntryExtended{EntityRegistryEntry: types.EntityRegistryEntry{EntityID: mustParse(t, "light.bedside"), AreaID: &bedroom}},
+			types.EntityRegistryEntryExtended{EntityRegistryEntry: types.EntityRegistryEntry{EntityID: mustParse(t, "light.ceiling"), AreaID: &bedroom}},
+		),
+	)
+	client := newTestClient(t, server, WithRegistryCache())
+	ctx := context.Background()
+
+	registries, err := client.Registries()
+	require.NoError(t, err)
+	assert.Len(t, registries.Entities, 2)
+	assert.Len(t, registries.Floors, 1)
+
+	label, err := client.CreateLabel(ctx, LabelParams{Name: "Night"})
+	require.NoError(t, err)
+
+	_, err = client.UpdateEntityRegistryEntry(ctx, mustParse(t, "light.bedside"), EntityRegistryUpdate{Labels: []string{label.LabelID}})
+	require.NoError(t, err)
+
+	night := func() entity.IDList {
+		registries, err := client.Registries()
+		require.NoError(t, err)
+
+		floor, _ := registries.FloorByName("upstairs")
+		label, _ := registries.LabelByName("night")
+
+		return registries.Filter(types.EntityFilter{Domain: domains.Light, FloorID: floor.FloorID, LabelID: label.LabelID})
+	}
+
+	assert.Eventually(t, func() bool {
+		return slices.Equal(night(), entity.IDList{mustParse(t, "light.bedside")})
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = newTestClient(t, server).Registries()
+	assert.ErrorIs(t, err, ErrRegistryCacheDisabled)
+}
+
+func TestCompressedStates(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", map[string]any{"friendly_name": "Kitchen", "brightness": nil})
+	server.SetState("sensor.temperature", "21.5", map[string]any{"unit_of_measurement": "°C"})
+
+	client := newTestClient(t, server, WithCompressedStates())
+
+	assertState := func(t *testing.T, id entity.ID) {
+		t.Helper()
+
+		expected, ok := server.State(id.String())
+		require.True(t, ok)
+
+		actual, err := client.Entity(id)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected.State, actual.State)
+		assert.JSONEq(t, string(expected.Attributes), string(actual.Attributes))
+		assert.Equal(t, expected.Context.ID, actual.Context.ID)
+		assert.True(t, expected.LastChanged.Equal(actual.LastChanged))
+		assert.True(t, expected.LastUpdated.Equal(actual.LastUpdated))
+	}
+
+	assert.Len(t, client.Entities(), 2)
+	assertState(t, kitchen)
+
+	changes := make(chan *types.StateChange, 1)
+	_, err := client.AddEntityListener(kitchen, func(change *types.StateChange) {
+		changes <- change
+	})
+	require.NoError(t, err)
+
+	server.SetState("light.kitchen", "on", map[string]any{"friendly_name": "Kitchen", "brightness": 180, "color_mode": "brightness"})
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, state.Value("off"), change.OldState.State)
+		assert.Equal(t, state.Value("on"), change.NewState.State)
+	case <-time.After(time.Second):
+		t.Fatal("entity listener was not called")
+	}
+
+	assertState(t, kitchen)
+
+	server.SetState("light.kitchen", "on", map[string]any{"friendly_name": "Kitchen"})
+	<-changes
+	assertState(t, kitchen)
+
+	server.RemoveState("sensor.temperature")
+	assert.Eventually(t, func() bool {
+		_, err := client.Entity(mustParse(t, "sensor.temperature"))
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	filtered := newTestClient(t, server, WithCompressedStates(kitchen))
+	assert.Len(t, filtered.Entities(), 1)
+
+	server.SetState("switch.fan", "on", nil)
+	assert.Eventually(t, func() bool { return len(client.Entities()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Len(t, filtered.Entities(), 1)
+	assert.Empty(t, server.Messages("get_states"))
+}
+
+func TestFilteredStates(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	hallway := mustParse(t, "light.hallway")
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+	server.SetState("light.hallway", "off", nil)
+	server.SetState("sensor.temperature", "21.5", nil)
+
+	client := newTestClient(t, server, WithFilteredStates(), WithReconnectBackoff(Backoff{Initial: 10 * time.Millisecond}))
+	assert.Empty(t, client.Entities())
+
+	// The entity IDs of the most recent subscribe_entities command.
+	subscribed := func() []string {
+		messages := server.Messages("subscribe_entities")
+		require.NotEmpty(t, messages)
+
+		var request struct {
+			EntityIDs []string `json:"entity_ids"`
+		}
+		require.NoError(t, messages[len(messages)-1].Decode(&request))
+
+		return request.EntityIDs
+	}
+
+	kitchenChanges := make(chan *types.StateChange, 2)
+	kitchenSub, err := client.AddEntityListener(kitchen, func(change *types.StateChange) {
+		kitchenChanges <- change
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.kitchen"}, subscribed())
+	assert.Len(t, client.Entities(), 1)
+
+	// Only entities that aren't streamed yet are subscribed to
+	hallwayChanges := make(chan *types.StateChange, 2)
+	_, err = client.AddEntityListener(hallway, func(change *types.StateChange) {
+		hallwayChanges <- change
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light.hallway"}, subscribed())
+	assert.Len(t, client.Entities(), 2)
+
+	_, err = client.AddEntitiesListener([]entity.ID{kitchen, hallway}, func(*types.StateChange) {})
+	require.NoError(t, err)
+	assert.Len(t, server.Messages("subscribe_entities"), 2)
+
+	server.SetState("light.kitchen", "on", nil)
+
+	select {
+	case change := <-kitchenChanges:
+		assert.Equal(t, state.Value("on"), change.NewState.State)
+	case <-time.After(time.Second):
+		t.Fatal("entity listener was not called")
+	}
+
+	_, err = client.AddEntityListener(mustParse(t, "light.missing"), func(*types.StateChange) {})
+	require.Error(t, err)
+	assert.Equal(t, []string{"light.missing"}, subscribed())
+	assert.Len(t, server.Messages("unsubscribe_events"), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.AddEntityListenerContext(ctx, mustParse(t, "sensor.temperature"), func(*types.StateChange) {})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The kitchen subscription stays while another listener uses it
+	require.NoError(t, kitchenSub.Unsubscribe())
+	assert.Len(t, server.Messages("unsubscribe_events"), 1)
+	assert.Len(t, client.Entities(), 2)
+
+	// Reconnecting replaces the subscriptions with one for every listened entity
+	server.Disconnect()
+
+	assert.Eventually(t, func() bool {
+		return len(server.Messages("subscribe_entities")) == 4 && client.ConnectionState() == StateConnected
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"light.hallway", "light.kitchen"}, subscribed())
+
+	server.SetState("light.hallway", "on", nil)
+
+	select {
+	case change := <-hallwayChanges:
+		assert.Equal(t, state.Value("on"), change.NewState.State)
+	case <-time.After(time.Second):
+		t.Fatal("entity listener was not called")
+	}
+
+	assert.Empty(t, kitchenChanges)
+	assert.Empty(t, hallwayChanges)
+	assert.Empty(t, server.Messages("get_states"))
+
+	_, err = client.AddRegexEntityListener(`^light\.`, func(*types.StateChange) {})
+	assert.ErrorIs(t, err, ErrRegexListenerFiltered)
+}
+
+func TestFilteredStatesContextDeadline(t *testing.T) {
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+	server.SetState("light.hallway", "off", nil)
+
+	client := newTestClient(t, server,
+		WithFilteredStates(),
+		WithDialer(func(ctx context.Context, url string) (Conn, error) {
+			conn, err := dialWebsocket(ctx, url)
+			return slowEvents{conn}, err
+		}),
+		func(c *Client) {
+			c.timeout = 50 * time.Millisecond
+		},
+	)
+
+	// A deadline longer than the client timeout is honoured
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := client.AddEntityListenerContext(ctx, mustParse(t, "light.kitchen"), func(*types.StateChange) {})
+	require.NoError(t, err)
+
+	// Without one the client timeout applies
+	_, err = client.AddEntityListener(mustParse(t, "light.hallway"), func(*types.StateChange) {})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Delays every event it reads.
+type slowEvents struct {
+	Conn
+}
+
+func (c slowEvents) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.Conn.ReadMessage()
+	if strings.Contains(string(data), `"type":"event"`) {
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	return messageType, data, err
+}
+
+func TestSubscribeTemplate(t *testing.T) {
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+	server.HandleTemplate(func(template string, _ map[string]any) (string, error) {
+		kitchen, _ := server.State("light.kitchen")
+		if template == "{{ broken" || kitchen.State == "unavailable" {
+			return "", errors.New("unable to render")
+		}
+
+		return "Kitchen is " + kitchen.State.String(), nil
+	})
+
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	_, err := client.SubscribeTemplate(ctx, "{{ broken", nil, func(types.TemplateRender) {})
+	require.Error(t, err)
+
+	renders := make(chan types.TemplateRender, 3)
+	sub, err := client.SubscribeTemplate(ctx, "Kitchen is {{ states('light.kitchen') }}", nil, func(render types.TemplateRender) {
+		renders <- render
+	}, TemplateStrict(), TemplateReportErrors(), TemplateTimeout(time.Second))
+	require.NoError(t, err)
+
+	next := func() types.TemplateRender {
+		select {
+		case render := <-renders:
+			return render
+		case <-time.After(time.Second):
+			t.Fatal("template callback was not called")
+		}
+
+		return types.TemplateRender{}
+	}
+
+	render := next()
+	assert.Equal(t, "Kitchen is off", render.String())
+	assert.True(t, render.Listeners.All)
+
+	server.SetState("light.kitchen", "on", nil)
+	assert.Equal(t, "Kitchen is on", next().String())
+
+	server.SetState("light.kitchen", "unavailable", nil)
+	render = next()
+	assert.Equal(t, "unable to render", render.Error)
+	assert.Equal(t, "ERROR", render.Level)
+
+	var request map[string]any
+	require.NoError(t, server.Messages("render_template")[1].Decode(&request))
+	assert.Equal(t, true, request["strict"])
+	assert.Equal(t, 1.0, request["timeout"])
+
+	require.NoError(t, sub.Unsubscribe())
+}
+
+func TestValidateConfig(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	validation, err := client.ValidateConfig(ctx,
+		trigger.State{EntityID: entity.IDList{mustParse(t, "light.kitchen")}, To: []string{"on"}},
+		[]map[string]any{{"entity_id": "sun.sun", "state": "below_horizon"}},
+		nil,
+	)
+	require.NoError(t, err)
+
+	assert.True(t, validation.Trigger.Valid)
+	assert.False(t, validation.Condition.Valid)
+	assert.Nil(t, validation.Action)
+	assert.False(t, validation.Valid())
+	assert.EqualError(t, validation.Err(), "condition: required key not provided @ data['condition']")
+}
+
+func TestExecuteScript(t *testing.T) {
+	server := hatest.NewServer(t)
+	server.HandleService("weather", "get_forecasts", func(hatest.ServiceCall) (any, error) {
+		return map[string]any{"weather.home": map[string]any{"forecast": []any{}}}, nil
+	})
+
+	client := newTestClient(t, server)
+
+	var response map[string]any
+	scriptCtx, err := client.ExecuteScript(context.Background(), []map[string]any{
+		{"action": "light.turn_on", "target": map[string]any{"entity_id": []string{"light.kitchen"}}},
+		{"action": "weather.get_forecasts", "data": map[string]any{"type": "daily"}, "response_variable": "forecast"},
+		{"stop": "done", "response_variable": "forecast"},
+		{"action": "light.turn_off"},
+	}, map[string]any{"brightness": 100}, &response)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, scriptCtx.ID)
+	assert.Contains(t, response, "weather.home")
+
+	calls := server.ServiceCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "turn_on", calls[0].Service)
+	assert.Equal(t, "get_forecasts", calls[1].Service)
+}
+
+func TestHistory(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	start := time.Now().Add(-time.Minute)
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", map[string]any{"brightness": nil})
+	server.SetState("light.kitchen", "on", map[string]any{"brightness": 200})
+	server.SetState("light.hallway", "on", nil)
+
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	history, err := client.HistoryDuringPeriod(ctx, start, entity.IDList{kitchen}, HistoryOptions{MinimalResponse: true})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Len(t, history[kitchen], 2)
+
+	latest, _ := server.State("light.kitchen")
+	assert.Equal(t, state.Value("on"), history[kitchen][1].State)
+	assert.True(t, latest.LastUpdated.Equal(history[kitchen][1].LastUpdated))
+	assert.NotNil(t, history[kitchen][0].Attributes)
+	assert.Nil(t, history[kitchen][1].Attributes)
+
+	streams := make(chan types.HistoryStream, 2)
+	sub, err := client.StreamHistory(ctx, start, entity.IDList{kitchen}, HistoryOptions{}, func(stream types.HistoryStream) {
+		streams <- stream
+	})
+	require.NoError(t, err)
+
+	next := func() types.HistoryStream {
+		select {
+		case stream := <-streams:
+			return stream
+		case <-time.After(time.Second):
+			t.Fatal("history stream callback was not called")
+		}
+
+		return types.HistoryStream{}
+	}
+
+	assert.Len(t, next().States[kitchen], 2)
+
+	server.SetState("light.hallway", "off", nil)
+	server.SetState("light.kitchen", "off", nil)
+
+	live := next()
+	require.Len(t, live.States, 1)
+	assert.Equal(t, state.Value("off"), live.States[kitchen][0].State)
+	require.NoError(t, sub.Unsubscribe())
+}
+
+func TestStreamLogbook(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "on", nil)
+
+	client := newTestClient(t, server)
+
+	streams := make(chan types.LogbookStream, 2)
+	_, err := client.StreamLogbook(context.Background(), start, LogbookOptions{}, func(stream types.LogbookStream) {
+		streams <- stream
+	})
+	require.NoError(t, err)
+
+	server.SetState("light.kitchen", "off", nil)
+
+	for _, expected := range []string{"on", "off"} {
+		select {
+		case stream := <-streams:
+			require.NotEmpty(t, stream.Events)
+			event := stream.Events[len(stream.Events)-1]
+			assert.Equal(t, expected, *event.State)
+			assert.Equal(t, "light.kitchen", event.EntityID.String())
+			assert.False(t, event.When.Time().IsZero())
+		case <-time.After(time.Second):
+			t.Fatal("logbook stream callback was not called")
+		}
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	server := hatest.NewServer(t)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	name, unit, unitClass := "Grid usage", "kWh", "energy"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var stats []StatisticImport
+
+	for hour := range 48 {
+		sum := float64(hour + 1)
+		stats = append(stats, StatisticImport{Start: start.Add(time.Duration(hour) * time.Hour), State: &sum, Sum: &sum})
+	}
+
+	err := client.ImportStatistics(ctx, StatisticImportMetadata{
+		StatisticID:       "energy_provider:usage",
+		Source:            "energy_provider",
+		Name:              &name,
+		UnitOfMeasurement: &unit,
+		UnitClass:         &unitClass,
+		HasSum:            true,
+	}, stats)
+	require.NoError(t, err)
+
+	temperature := "temperature"
+	err = client.ImportStatistics(ctx, StatisticImportMetadata{
+		StatisticID:       "energy_provider:other",
+		Source:            "energy_provider",
+		UnitOfMeasurement: &unit,
+		UnitClass:         &temperature,
+	}, stats)
+	require.Error(t, err)
+
+	invalid := []StatisticImport{{Start: start.Add(time.Minute)}}
+	err = client.ImportStatistics(ctx, StatisticImportMetadata{StatisticID: "sensor.power", Source: "recorder"}, invalid)
+	require.Error(t, err)
+
+	means, err := client.ListStatisticIDs(ctx, types.StatisticTypeMean)
+	require.NoError(t, err)
+	assert.Empty(t, means)
+
+	metadata, err := client.GetStatisticsMetadata(ctx, "energy_provider:usage")
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+	assert.True(t, metadata[0].HasSum)
+	require.NotNil(t, metadata[0].UnitClass)
+	assert.Equal(t, "energy", *metadata[0].UnitClass)
+	require.NotNil(t, metadata[0].MeanType)
+	assert.Equal(t, types.StatisticMeanTypeNone, *metadata[0].MeanType)
+
+	daily, err := client.StatisticsDuringPeriod(ctx, start.Add(time.Hour), []string{"energy_provider:usage"}, types.StatisticPeriodDay, StatisticsOptions{
+		EndTime: start.Add(72 * time.Hour),
+		Types:   []types.StatisticType{types.StatisticTypeSum, types.StatisticTypeChange},
+		Units:   map[string]string{"energy": "Wh"},
+	})
+	require.NoError(t, err)
+	require.Len(t, daily["energy_provider:usage"], 2)
+
+	first, second := daily["energy_provider:usage"][0], daily["energy_provider:usage"][1]
+	assert.True(t, start.Equal(first.Start.Time()))
+	assert.True(t, start.Add(24*time.Hour).Equal(first.End.Time()))
+	assert.Equal(t, 24.0, *first.Sum)
+	assert.Equal(t, 23.0, *first.Change)
+	assert.Equal(t, 24.0, *second.Change)
+	assert.Nil(t, second.State)
+
+	msgs := server.Messages("recorder/statistics_during_period")
+	require.Len(t, msgs, 1)
+
+	var request map[string]any
+	require.NoError(t, msgs[0].Decode(&request))
+	assert.Equal(t, map[string]any{"energy": "Wh"}, request["units"])
+	assert.Equal(t, "2024-01-01T01:00:00.000000Z", request["start_time"])
+}
+
+type stateChange struct {
+	state ConnectionState
+	err   error
+}
+
+func expectStates(t *testing.T, changes <-chan stateChange, want ...ConnectionState) []error {
+	t.Helper()
+
+	errs := make([]error, 0, len(want))
+
+	for _, state := range want {
+		select {
+		case change := <-changes:
+			require.Equal(t, state, change.state)
+			errs = append(errs, change.err)
+		case <-time.After(time.Second):
+			t.Fatalf("connection state did not change to %s", state)
+		}
+	}
+
+	return errs
+}
+
+func TestReconnect(t *testing.T) {
+	server := hatest.NewServer(t)
+	changes := make(chan stateChange, 16)
+	client := newTestClient(t, server,
+		WithReconnectBackoff(Backoff{Initial: 10 * time.Millisecond}),
+		WithConnectionStateHandler(func(state ConnectionState, err error) {
+			changes <- stateChange{state, err}
+		}),
+	)
+
+	expectStates(t, changes, StateConnecting, StateAuthenticating, StateConnected)
+
+	events := make(chan types.Event, 1)
+	_, err := client.SubscribeToEvent("custom_event", func(e types.Event) {
+		events <- e
+	})
+	require.NoError(t, err)
+
+	server.Disconnect()
+
+	errs := expectStates(t, changes, StateDisconnected, StateConnecting, StateAuthenticating, StateConnected)
+	assert.Error(t, errs[0])
+	assert.Equal(t, StateConnected, client.ConnectionState())
+
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected change to %s after reconnecting", change.state)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	server.FireEvent("custom_event", nil)
+
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not replayed after reconnecting")
+	}
+
+	client.Close()
+
+	errs = expectStates(t, changes, StateDisconnected)
+	assert.NoError(t, errs[0])
+}
+
+func TestReconnectResync(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	hallway := mustParse(t, "light.hallway")
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+	server.SetState("light.hallway", "on", nil)
+
+	// Hold reconnects back until the states have changed
+	var dials atomic.Int32
+
+	release := make(chan struct{})
+	client := newTestClient(t, server,
+		WithDialer(func(ctx context.Context, url string) (Conn, error) {
+			if dials.Add(1) > 1 {
+				<-release
+			}
+
+			return dialWebsocket(ctx, url)
+		}),
+		WithReconnectBackoff(Backoff{Initial: 10 * time.Millisecond}),
+	)
+
+	changes := make(chan *types.StateChange, 4)
+	_, err := client.AddEntitiesListener([]entity.ID{kitchen, hallway}, func(change *types.StateChange) {
+		changes <- change
+	})
+	require.NoError(t, err)
+
+	triggers := make(chan types.Trigger, 1)
+	_, err = client.SubscribeToTrigger(map[string]any{"platform": "state"}, func(trigger types.Trigger) {
+		triggers <- trigger
+	})
+	require.NoError(t, err)
+
+	client.mu.RLock()
+	oldIDs := slices.Collect(maps.Keys(client.triggerHandler))
+	client.mu.RUnlock()
+
+	server.Disconnect()
+	require.Eventually(t, func() bool { return dials.Load() == 2 }, time.Second, time.Millisecond)
+
+	server.SetState("light.kitchen", "on", nil)
+	server.RemoveState("light.hallway")
+	close(release)
+
+	// Changes made while disconnected are reported once resynced
+	received := make(map[entity.ID]*types.StateChange)
+
+	for range 2 {
+		select {
+		case change := <-changes:
+			received[change.EntityID] = change
+		case <-time.After(time.Second):
+			t.Fatal("entity listener was not called after resyncing")
+		}
+	}
+
+	require.Contains(t, received, kitchen)
+	assert.Equal(t, state.Value("off"), received[kitchen].OldState.State)
+	assert.Equal(t, state.Value("on"), received[kitchen].NewState.State)
+
+	require.Contains(t, received, hallway)
+	assert.Equal(t, state.Value("on"), received[hallway].OldState.State)
+	assert.Nil(t, received[hallway].NewState)
+
+	entities := client.Entities()
+	assert.Equal(t, state.Value("on"), entities[kitchen].State)
+	assert.NotContains(t, entities, hallway)
+
+	// The trigger subscription is replayed under a new ID
+	client.mu.RLock()
+	newIDs := slices.Collect(maps.Keys(client.triggerHandler))
+	client.mu.RUnlock()
+
+	require.Len(t, newIDs, 1)
+	assert.NotEqual(t, oldIDs, newIDs)
+
+	server.FireTrigger(map[string]any{"platform": "state", "entity_id": "light.kitchen"})
+
+	select {
+	case <-triggers:
+	case <-time.After(time.Second):
+		t.Fatal("trigger subscription was not replayed after reconnecting")
+	}
+}
+
+func TestReconnectMaxAttempts(t *testing.T) {
+	server := hatest.NewServer(t)
+	changes := make(chan stateChange, 16)
+
+	var dials atomic.Int32
+
+	newTestClient(t, server,
+		WithDialer(func(ctx context.Context, url string) (Conn, error) {
+			if dials.Add(1) > 1 {
+				return nil, errors.New("connection refused")
+			}
+
+			return dialWebsocket(ctx, url)
+		}),
+		WithReconnectBackoff(Backoff{Initial: time.Millisecond, MaxAttempts: 2}),
+		WithConnectionStateHandler(func(state ConnectionState, err error) {
+			changes <- stateChange{state, err}
+		}),
+	)
+
+	expectStates(t, changes, StateConnecting, StateAuthenticating, StateConnected)
+
+	server.Disconnect()
+
+	errs := expectStates(t, changes, StateDisconnected, StateConnecting, StateDisconnected, StateConnecting, StateDisconnected)
+	assert.NotErrorIs(t, errs[2], ErrReconnectFailed)
+	assert.ErrorIs(t, errs[4], ErrReconnectFailed)
+	assert.Equal(t, int32(3), dials.Load())
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+
+	assert.Equal(t, time.Second, backoff.delay(1))
+	assert.Equal(t, 4*time.Second, backoff.delay(3))
+	assert.Equal(t, 5*time.Second, backoff.delay(10))
+
+	backoff.Jitter = 0.5
+	for range 100 {
+		assert.InDelta(t, float64(4*time.Second), float64(backoff.delay(3)), float64(2*time.Second))
+	}
+}
+
+func TestWaitForRunning(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+	server.SetCoreState(config.StateStarting)
+
+	client := newTestClient(t, server, WithWaitForRunning())
+	assert.False(t, client.Running())
+
+	ctx := context.Background()
+	started := make(chan struct{}, 2)
+	stopping := make(chan struct{}, 1)
+
+	_, err := client.OnHomeAssistantStarted(ctx, func() { started <- struct{}{} })
+	require.NoError(t, err)
+	_, err = client.OnHomeAssistantStopping(ctx, func() { stopping <- struct{}{} })
+	require.NoError(t, err)
+
+	changes := make(chan *types.StateChange, 4)
+	_, err = client.AddEntityListener(kitchen, func(change *types.StateChange) {
+		changes <- change
+	})
+	require.NoError(t, err)
+
+	server.SetState("light.kitchen", "unavailable", nil)
+	server.SetState("light.kitchen", "on", nil)
+
+	assert.Eventually(t, func() bool {
+		e, err := client.Entity(kitchen)
+		return err == nil && e.State == state.Value("on")
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatal("listener was called before home assistant was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	server.SetCoreState(config.StateRunning)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("started hook was not called")
+	}
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, state.Value("off"), change.OldState.State)
+		assert.Equal(t, state.Value("on"), change.NewState.State)
+	case <-time.After(time.Second):
+		t.Fatal("held back change was not released")
+	}
+
+	assert.True(t, client.Running())
+
+	_, err = client.OnHomeAssistantStarted(ctx, func() { started <- struct{}{} })
+	require.NoError(t, err)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("started hook was not called right away while running")
+	}
+
+	server.SetCoreState(config.StateStopping)
+
+	select {
+	case <-stopping:
+	case <-time.After(time.Second):
+		t.Fatal("stopping hook was not called")
+	}
+
+	assert.False(t, client.Running())
+	assert.Empty(t, changes)
+}
+
+// Return the IDs of the goroutines running code from this package, other than
+// tests. Like goleak, but without the dependency.
+func clientGoroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	goroutines := make(map[string]string)
+
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if !strings.Contains(stack, "go-homeassistant/websocket.") || strings.Contains(stack, "testing.tRunner") {
+			continue
+		}
+
+		id, _, _ := strings.Cut(strings.TrimPrefix(stack, "goroutine "), " ")
+		goroutines[id] = stack
+	}
+
+	return goroutines
+}
+
+func requireNoLeaks(t *testing.T, before map[string]string) {
+	t.Helper()
+
+	var leaked []string
+
+	for range 100 {
+		leaked = leaked[:0]
+
+		for id, stack := range clientGoroutines() {
+			if _, existed := before[id]; !existed {
+				leaked = append(leaked, stack)
+			}
+		}
+
+		if len(leaked) == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("leaked goroutines:\n\n%s", strings.Join(leaked, "\n\n"))
+}
+
+func TestLifecycleSubscribeFailed(t *testing.T) {
+	server := hatest.NewServer(t)
+	server.Handle("subscribe_events", func(_ *hatest.Conn, msg hatest.Message) (any, error) {
+		var request struct {
+			EventType string `json:"event_type"`
+		}
+		if err := msg.Decode(&request); err != nil {
+			return nil, err
+		}
+
+		if request.EventType == "homeassistant_stop" {
+			return nil, hatest.Error{Code: "unknown_error", Message: "failed"}
+		}
+
+		return nil, nil
+	})
+
+	client := newTestClient(t, server)
+
+	_, err := client.OnHomeAssistantStarted(context.Background(), func() {})
+	require.Error(t, err)
+
+	// The subscription that worked is cancelled again
+	assert.Len(t, server.Messages("unsubscribe_events"), 1)
+
+	client.mu.RLock()
+	for _, handler := range client.eventHandler {
+		assert.NotEqual(t, "homeassistant_started", handler.EventType)
+	}
+	client.mu.RUnlock()
+}
+
+func TestShutdown(t *testing.T) {
+	before := clientGoroutines()
+
+	kitchen := mustParse(t, "light.kitchen")
+	server := hatest.NewServer(t)
+	server.SetState("light.kitchen", "off", nil)
+
+	panels := make(chan struct{})
+	server.Handle("get_panels", func(*hatest.Conn, hatest.Message) (any, error) {
+		<-panels
+		return types.Panels{}, nil
+	})
+
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	running := make(chan struct{})
+	release := make(chan struct{})
+	_, err := client.AddEntityListener(kitchen, func(*types.StateChange) {
+		close(running)
+		<-release
+	})
+	require.NoError(t, err)
+
+	_, err = client.SubscribeToEvent("custom_event", func(types.Event) {})
+	require.NoError(t, err)
+
+	inFlight := make(chan error, 1)
+
+	go func() {
+		_, err := client.GetPanelsContext(ctx)
+		inFlight <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(server.Messages("get_panels")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	server.SetState("light.kitchen", "on", nil)
+	<-running
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+
+	go func() {
+		shutdown <- client.Shutdown(shutdownCtx)
+	}()
+
+	assert.Eventually(t, client.isClosed, time.Second, time.Millisecond)
+
+	_, err = client.GetConfigContext(ctx)
+	require.ErrorIs(t, err, ErrClientClosed)
+
+	close(panels)
+	require.NoError(t, <-inFlight)
+
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned while a callback was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdown)
+
+	assert.Len(t, server.Messages("unsubscribe_events"), 2)
+	assert.Equal(t, StateDisconnected, client.ConnectionState())
+	assert.NoError(t, client.Shutdown(ctx))
+
+	requireNoLeaks(t, before)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	before := clientGoroutines()
+
+	server := hatest.NewServer(t)
+
+	var dials atomic.Int32
+
+	client := newTestClient(t, server,
+		WithDialer(func(ctx context.Context, url string) (Conn, error) {
+			if dials.Add(1) > 1 {
+				return nil, errors.New("connection refused")
+			}
+
+			return dialWebsocket(ctx, url)
+		}),
+		WithReconnectBackoff(Backoff{Initial: time.Hour}),
+	)
+
+	running := make(chan struct{})
+	release := make(chan struct{})
+	_, err := client.SubscribeToEvent("custom_event", func(types.Event) {
+		close(running)
+		<-release
+	})
+	require.NoError(t, err)
+
+	server.FireEvent("custom_event", nil)
+	<-running
+
+	// Shutdown while waiting to retry a failed reconnect, with a callback that
+	// outlives the deadline
+	server.Disconnect()
+	require.Eventually(t, func() bool {
+		return dials.Load() == 2 && client.ConnectionState() == StateDisconnected
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, client.Shutdown(ctx), context.DeadlineExceeded)
+
+	close(release)
+	requireNoLeaks(t, before)
+}
+
+func TestShutdownDuringAuth(t *testing.T) {
+	before := clientGoroutines()
+
+	server := hatest.NewServer(t)
+	changes := make(chan stateChange, 16)
+	client := newTestClient(t, server,
+		func(c *Client) { c.timeout = time.Minute },
+		WithReconnectBackoff(Backoff{Initial: time.Millisecond}),
+		WithConnectionStateHandler(func(state ConnectionState, err error) {
+			changes <- stateChange{state, err}
+		}),
+	)
+
+	expectStates(t, changes, StateConnecting, StateAuthenticating, StateConnected)
+
+	// The reconnect is left waiting for auth_ok, well within the client timeout
+	server.HoldAuth()
+	server.Disconnect()
+	expectStates(t, changes, StateDisconnected, StateConnecting, StateAuthenticating)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Shutdown(ctx))
+	requireNoLeaks(t, before)
+}
@@ -1,10 +1,12 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
 
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/domains"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
 )
 
@@ -13,7 +15,17 @@ type subscribeToEventRequest struct {
 	EventType string `json:"event_type,omitempty"`
 }
 
-func (c *Client) SubscribeToEvent(eventType string, f func(types.Event)) error {
+func (c *Client) SubscribeToEvent(eventType string, f func(types.Event)) (*Subscription, error) {
+	return c.SubscribeToEventContext(context.Background(), eventType, f)
+}
+
+// SubscribeToEventContext is like SubscribeToEvent but uses the provided context
+// for the subscribe command.
+func (c *Client) SubscribeToEventContext(
+	ctx context.Context,
+	eventType string,
+	f func(types.Event),
+) (*Subscription, error) {
 	request := subscribeToEventRequest{
 		baseMessage: baseMessage{
 			Type: messageTypeSubscribeEvent,
@@ -21,21 +33,30 @@ func (c *Client) SubscribeToEvent(eventType string, f func(types.Event)) error {
 		EventType: eventType,
 	}
 
-	if err := c.write(&request, nil); err != nil {
+	if err := c.subscribeToEvent(ctx, &request, eventHandler{EventType: eventType, Callback: f}); err != nil {
 		c.logger.Error("failed to subscribe to event: %w", err)
-		return err
-	}
-
-	c.mu.Lock()
-	c.eventHandler[request.ID] = eventHandler{
-		EventType: eventType,
-		Callback:  f,
+		return nil, err
 	}
-	c.mu.Unlock()
 
 	c.logger.Info("subscribed to %s", eventType)
 
-	return nil
+	return newSubscription(func(ctx context.Context) error {
+		return c.unsubscribeEvents(ctx, &request, func(id int64) {
+			delete(c.eventHandler, id)
+		})
+	}), nil
+}
+
+// Send a subscribe_events request, registering the handler under the request ID
+// before it is sent so no events are missed between the result and registration.
+func (c *Client) subscribeToEvent(ctx context.Context, request *subscribeToEventRequest, handler eventHandler) error {
+	handler.request = request
+
+	return c.write(ctx, request, nil, registerHandler(func(id int64) {
+		c.eventHandler[id] = handler
+	}, func(id int64) {
+		delete(c.eventHandler, id)
+	}))
 }
 
 type subscribeToTriggerRequest struct {
@@ -43,7 +64,19 @@ type subscribeToTriggerRequest struct {
 	Trigger any `json:"trigger"`
 }
 
-func (c *Client) SubscribeToTrigger(trigger any, f func(types.Trigger)) error {
+// SubscribeToTrigger subscribes to one or more triggers, such as those in the
+// shared/trigger package, and calls f with the trigger variables each time it fires.
+func (c *Client) SubscribeToTrigger(trigger any, f func(types.Trigger)) (*Subscription, error) {
+	return c.SubscribeToTriggerContext(context.Background(), trigger, f)
+}
+
+// SubscribeToTriggerContext is like SubscribeToTrigger but uses the provided context
+// for the subscribe command.
+func (c *Client) SubscribeToTriggerContext(
+	ctx context.Context,
+	trigger any,
+	f func(types.Trigger),
+) (*Subscription, error) {
 	request := subscribeToTriggerRequest{
 		baseMessage: baseMessage{
 			Type: messageTypeSubscribeTrigger,
@@ -51,18 +84,32 @@ func (c *Client) SubscribeToTrigger(trigger any, f func(types.Trigger)) error {
 		Trigger: trigger,
 	}
 
-	if err := c.write(&request, nil); err != nil {
-		c.logger.Error("failed to subscribe to trigger: %w", err)
-		return err
+	handler := triggerHandler{
+		Callback: f,
+		request:  &request,
 	}
 
-	c.mu.Lock()
-	c.triggerHandler[request.ID] = append(c.triggerHandler[request.ID], triggerHandler{Callback: f})
-	c.mu.Unlock()
+	if err := c.write(ctx, &request, nil, registerHandler(func(id int64) {
+		c.triggerHandler[id] = handler
+	}, func(id int64) {
+		delete(c.triggerHandler, id)
+	})); err != nil {
+		c.logger.Error("failed to subscribe to trigger: %w", err)
+		return nil, err
+	}
 
 	c.logger.Info("subscribed to trigger: %+v", trigger)
 
-	return nil
+	return newSubscription(func(ctx context.Context) error {
+		return c.unsubscribeEvents(ctx, &request, func(id int64) {
+			delete(c.triggerHandler, id)
+		})
+	}), nil
+}
+
+// Result of commands that only return the context they ran in.
+type contextResult struct {
+	Context types.Context `json:"context"`
 }
 
 type fireEventRequest struct {
@@ -72,6 +119,11 @@ type fireEventRequest struct {
 }
 
 func (c *Client) FireEvent(eventType string, eventData any) (types.Context, error) {
+	return c.FireEventContext(context.Background(), eventType, eventData)
+}
+
+// FireEventContext is like FireEvent but uses the provided context.
+func (c *Client) FireEventContext(ctx context.Context, eventType string, eventData any) (types.Context, error) {
 	request := fireEventRequest{
 		baseMessage: baseMessage{
 			Type: messageTypeFireEvent,
@@ -80,33 +132,34 @@ func (c *Client) FireEvent(eventType string, eventData any) (types.Context, erro
 		EventData: eventData,
 	}
 
-	var response types.Context
-	if err := c.write(&request, &response); err != nil {
+	var response contextResult
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to fire event: %w", err)
-		return response, err
+		return response.Context, err
 	}
 
 	c.logger.Info("fired %s event", eventType)
 
-	return response, nil
+	return response.Context, nil
 }
 
-type CallServiceParams struct {
-	Domain      string
-	Service     string
-	ServiceData any
-	Target      types.ServiceTarget
-}
+type CallServiceParams = types.CallServiceParams
 
 type callServiceMessage struct {
 	baseMessage
-	Domain      string              `json:"domain"`
-	Service     string              `json:"service"`
-	ServiceData any                 `json:"service_data,omitempty"`
-	Target      types.ServiceTarget `json:"target,omitempty"`
+	Domain         domains.Domain      `json:"domain"`
+	Service        string              `json:"service"`
+	ServiceData    any                 `json:"service_data,omitempty"`
+	Target         types.ServiceTarget `json:"target,omitempty"`
+	ReturnResponse bool                `json:"return_response,omitempty"`
 }
 
 func (c *Client) CallService(params CallServiceParams) (types.Context, error) {
+	return c.CallServiceContext(context.Background(), params)
+}
+
+// CallServiceContext is like CallService but uses the provided context.
+func (c *Client) CallServiceContext(ctx context.Context, params CallServiceParams) (types.Context, error) {
 	request := callServiceMessage{
 		baseMessage: baseMessage{
 			Type: messageTypeCallService,
@@ -117,41 +170,122 @@ func (c *Client) CallService(params CallServiceParams) (types.Context, error) {
 		Target:      params.Target,
 	}
 
-	var response types.Context
-	if err := c.write(&request, &response); err != nil {
+	var response contextResult
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to call service: %w", err)
-		return response, err
+		return response.Context, err
 	}
 
 	c.logger.Info("called %s.%s", params.Domain, params.Service)
 
-	return response, nil
+	return response.Context, nil
+}
+
+// CallServiceWithResponse calls a service that returns data, such as
+// weather.get_forecasts, and decodes the response into response. Calls to
+// services that don't return a response are rejected before being sent.
+func (c *Client) CallServiceWithResponse(
+	ctx context.Context,
+	params CallServiceParams,
+	response any,
+) (types.Context, error) {
+	if err := c.checkServiceResponse(ctx, params); err != nil {
+		return types.Context{}, err
+	}
+
+	request := callServiceMessage{
+		baseMessage: baseMessage{
+			Type: messageTypeCallService,
+		},
+		Domain:         params.Domain,
+		Service:        params.Service,
+		ServiceData:    params.ServiceData,
+		Target:         params.Target,
+		ReturnResponse: true,
+	}
+
+	var result struct {
+		contextResult
+		Response json.RawMessage `json:"response"`
+	}
+
+	if err := c.write(ctx, &request, &result); err != nil {
+		c.logger.Error("failed to call service: %w", err)
+		return result.Context, err
+	}
+
+	c.logger.Info("called %s.%s", params.Domain, params.Service)
+
+	if response != nil && result.Response != nil {
+		if err := json.Unmarshal(result.Response, response); err != nil {
+			return result.Context, fmt.Errorf("failed to unmarshal service response: %w", err)
+		}
+	}
+
+	return result.Context, nil
+}
+
+// Check that a service can return a response. Services are cached and only
+// fetched again when the service isn't known, in case it was added since.
+func (c *Client) checkServiceResponse(ctx context.Context, params CallServiceParams) error {
+	c.mu.RLock()
+	services := c.services
+	c.mu.RUnlock()
+
+	err := services.CheckResponse(params.Domain, params.Service)
+	if services != nil && !errors.Is(err, types.ErrServiceNotFound) {
+		return err
+	}
+
+	services, err = c.GetServicesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.services = services
+	c.mu.Unlock()
+
+	return services.CheckResponse(params.Domain, params.Service)
 }
 
 func (c *Client) GetStates() (types.EntitiesMap, error) {
+	return c.GetStatesContext(context.Background())
+}
+
+// GetStatesContext is like GetStates but uses the provided context.
+func (c *Client) GetStatesContext(ctx context.Context) (types.EntitiesMap, error) {
 	request := baseMessage{
 		Type: messageTypeGetStates,
 	}
 
 	var response types.Entities
-	if err := c.write(&request, &response); err != nil {
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to get states: %s", err.Error())
 		return nil, err
 	}
 
+	c.mu.Lock()
 	c.EntitiesMap = response.SortStates()
+	c.mu.Unlock()
+
 	c.logger.Info("states retrieved")
 
-	return c.EntitiesMap, nil
+	return c.Entities(), nil
 }
 
 func (c *Client) GetConfig() (types.Config, error) {
+	return c.GetConfigContext(context.Background())
+}
+
+// GetConfigContext is like GetConfig but uses the provided context.
+func (c *Client) GetConfigContext(ctx context.Context) (types.Config, error) {
 	request := baseMessage{
 		Type: messageTypeGetConfig,
 	}
 
 	var response types.Config
-	if err := c.write(&request, &response); err != nil {
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to get config: %w", err)
 		return types.Config{}, err
 	}
@@ -162,12 +296,17 @@ func (c *Client) GetConfig() (types.Config, error) {
 }
 
 func (c *Client) GetServices() (types.Services, error) {
+	return c.GetServicesContext(context.Background())
+}
+
+// GetServicesContext is like GetServices but uses the provided context.
+func (c *Client) GetServicesContext(ctx context.Context) (types.Services, error) {
 	request := baseMessage{
 		Type: messageTypeGetServices,
 	}
 
 	var response types.Services
-	if err := c.write(&request, &response); err != nil {
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to get services: %w", err)
 		return nil, err
 	}
@@ -178,12 +317,17 @@ func (c *Client) GetServices() (types.Services, error) {
 }
 
 func (c *Client) GetPanels() (types.Panels, error) {
+	return c.GetPanelsContext(context.Background())
+}
+
+// GetPanelsContext is like GetPanels but uses the provided context.
+func (c *Client) GetPanelsContext(ctx context.Context) (types.Panels, error) {
 	request := baseMessage{
 		Type: messageTypeGetPanels,
 	}
 
 	var response types.Panels
-	if err := c.write(&request, &response, skipHistory()); err != nil {
+	if err := c.write(ctx, &request, &response); err != nil {
 		c.logger.Error("failed to get panels: %w", err)
 		return nil, err
 	}
@@ -213,44 +357,88 @@ func (re responseError) Error() string {
 type (
 	writeOption  func(*writeOptions)
 	writeOptions struct {
-		skipHistory bool
+		whileClosing bool           // Sent by Shutdown itself, not counted as in flight
+		register     func(id int64) // Called with c.mu held before the request is sent
+		unregister   func(id int64) // Called with c.mu held if the request fails
 	}
 )
 
-func skipHistory() writeOption {
+func whileClosing() writeOption {
+	return func(c *writeOptions) {
+		c.whileClosing = true
+	}
+}
+
+// Register a handler under the request ID before the request is sent.
+// The handler is removed again if the request fails.
+func registerHandler(register, unregister func(id int64)) writeOption {
 	return func(c *writeOptions) {
-		c.skipHistory = true
+		c.register = register
+		c.unregister = unregister
 	}
 }
 
-func (c *Client) write(request cmdMessage, result any, options ...writeOption) error {
+// Send a command and wait for its result. The wait ends when a result arrives
+// or ctx is done. The client timeout only applies if ctx has no deadline, so
+// a longer deadline is honoured. The pending result channel is always removed
+// before returning.
+func (c *Client) write(ctx context.Context, request cmdMessage, result any, options ...writeOption) error {
 	opts := &writeOptions{}
 	for _, option := range options {
 		option(opts)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	id := c.getNextID()
+	responseChan := make(chan []byte, 1)
+
+	c.mu.Lock()
+	if !opts.whileClosing {
+		if c.closed {
+			c.mu.Unlock()
+			return ErrClientClosed
+		}
+
+		c.inflight.Add(1)
+		defer c.inflight.Done()
+	}
+
 	request.SetID(id)
 
-	if !opts.skipHistory {
-		c.msgHistory[id] = request
+	c.resultChan[id] = responseChan
+
+	if opts.register != nil {
+		opts.register(id)
 	}
+	c.mu.Unlock()
 
-	responseChan := make(chan []byte, 1)
+	err := c.send(ctx, id, request, responseChan, result)
 
 	c.mu.Lock()
-	c.resultChan[id] = responseChan
+	delete(c.resultChan, id)
+
+	if err != nil && opts.unregister != nil {
+		opts.unregister(id)
+	}
 	c.mu.Unlock()
 
-	defer func() {
-		c.mu.Lock()
-		delete(c.resultChan, id)
-		c.mu.Unlock()
-		close(responseChan)
-	}()
+	return err
+}
+
+// Write the request to the websocket and wait for its result.
+func (c *Client) send(ctx context.Context, id int64, request cmdMessage, responseChan chan []byte, result any) error {
+	conn := c.currentConn()
+	if conn == nil {
+		return ErrConnectionClosed
+	}
 
-	if err := c.wsConn.WriteJSON(request); err != nil {
-		// c.reconnectChan <- true
+	if err := conn.writeJSON(ctx, request); err != nil {
 		c.logger.Error("error sending message: %v", request)
 		return fmt.Errorf("error sending message: %v\nerror: %w", request, err)
 	}
@@ -277,8 +465,21 @@ func (c *Client) write(request cmdMessage, result any, options ...writeOption) e
 
 		return nil
 
-	case <-time.After(c.timeout):
-		c.logger.Error("response timeout for request ID: %d", id)
-		return fmt.Errorf("response timeout for request ID: %d", id)
+	case <-conn.done:
+		c.logger.Error("connection closed while waiting for request ID: %d", id)
+		return fmt.Errorf("request ID %d: %w", id, ErrConnectionClosed)
+
+	case <-ctx.Done():
+		c.logger.Error("request ID %d cancelled: %w", id, ctx.Err())
+		return fmt.Errorf("request ID %d: %w", id, ctx.Err())
+	}
+}
+
+// Apply the client timeout to ctx unless it already has a deadline.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
 	}
+
+	return context.WithTimeout(ctx, c.timeout)
 }
@@ -1,15 +1,134 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
 )
 
-// Lock and increment ID used in all messages sent to Home Assistant
+type (
+	// Conn is the transport between the client and Home Assistant. It is
+	// satisfied by *websocket.Conn from gorilla/websocket and only needs to
+	// support one concurrent reader and one concurrent writer.
+	Conn interface {
+		ReadMessage() (messageType int, p []byte, err error)
+		WriteMessage(messageType int, data []byte) error
+		SetWriteDeadline(t time.Time) error
+		Close() error
+	}
+
+	// Dialer opens a new Conn to the websocket API at url.
+	Dialer func(ctx context.Context, url string) (Conn, error)
+)
+
+// A single websocket connection to Home Assistant. gorilla/websocket allows
+// one concurrent reader and one concurrent writer, so every write is queued
+// for a dedicated writer goroutine and reads only happen in listen.
+type connection struct {
+	ws        Conn
+	timeout   time.Duration
+	outbound  chan outboundMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type outboundMessage struct {
+	payload []byte
+	result  chan error
+}
+
+func newConnection(ws Conn, timeout time.Duration) *connection {
+	conn := &connection{
+		ws:       ws,
+		timeout:  timeout,
+		outbound: make(chan outboundMessage, 64),
+		done:     make(chan struct{}),
+	}
+
+	return conn
+}
+
+// Write queued messages until the connection is closed.
+func (conn *connection) writeLoop() {
+	for {
+		select {
+		case msg := <-conn.outbound:
+			if err := conn.ws.SetWriteDeadline(time.Now().Add(conn.timeout)); err != nil {
+				msg.result <- err
+				continue
+			}
+
+			msg.result <- conn.ws.WriteMessage(websocket.TextMessage, msg.payload)
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+// Queue a message for the writer goroutine and wait until it has been written.
+func (conn *connection) writeJSON(ctx context.Context, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	msg := outboundMessage{
+		payload: payload,
+		result:  make(chan error, 1),
+	}
+
+	select {
+	case conn.outbound <- msg:
+	case <-conn.done:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-msg.result:
+		return err
+	case <-conn.done:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Read and decode the next message. Only used before listen takes over reading.
+// Conn has no read deadline, so the connection is closed if ctx is done first.
+func (conn *connection) readJSON(ctx context.Context, v any) error {
+	stop := context.AfterFunc(ctx, conn.close)
+	defer stop()
+
+	_, msg, err := conn.ws.ReadMessage()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return err
+	}
+
+	return json.Unmarshal(msg, v)
+}
+
+// Close the connection and stop its writer. Safe to call more than once.
+func (conn *connection) close() {
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		conn.ws.Close()
+	})
+}
+
+// Lock and increment ID used in all messages sent to Home Assistant.
+// IDs are never reset on reconnect so that subscriptions being replayed can't
+// collide with the IDs they were previously registered under.
 func (c *Client) getNextID() int64 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -19,22 +138,74 @@ func (c *Client) getNextID() int64 {
 	return c.msgID
 }
 
-// Dial and configure websocket connection
-func (c *Client) connect() error {
-	dialer := websocket.DefaultDialer
+// Return the active connection, or nil if there is none.
+func (c *Client) currentConn() *connection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.conn
+}
+
+// Report whether Close has been called.
+func (c *Client) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.closed
+}
+
+// Dial the websocket API with gorilla/websocket.
+func dialWebsocket(ctx context.Context, url string) (Conn, error) {
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// Dial and configure websocket connection. Gives up once ctx is done.
+func (c *Client) connect(ctx context.Context) (*connection, error) {
+	c.setConnectionState(StateConnecting, nil)
 
-	conn, resp, err := dialer.Dial(c.wsURL, nil)
+	dialCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	ws, err := c.dialer(dialCtx, c.wsURL)
 	if err != nil {
 		c.logger.Error("unable to dial home assistant: %w", err)
-		resp.Body.Close()
+
+		return nil, fmt.Errorf("unable to dial home assistant: %w", err)
 	}
 
-	c.wsConn = conn
-	if err := c.authenticate(); err != nil {
-		return err
+	c.setConnectionState(StateAuthenticating, nil)
+
+	if c.recorder != nil {
+		ws = c.recorder.wrap(ws, c.logger)
 	}
 
-	return nil
+	conn := newConnection(ws, c.timeout)
+	c.goRoutine(conn.writeLoop)
+	if err := c.authenticate(ctx, conn); err != nil {
+		conn.close()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	return conn, nil
+}
+
+// A connection that listen stopped reading from.
+type lostConnection struct {
+	conn *connection
+	err  error
 }
 
 type incomingMsg struct {
@@ -43,24 +214,34 @@ type incomingMsg struct {
 }
 
 // Listen to new messages as they come through on the websocket.
-// Messages are automatically sorted based on type.
-func (c *Client) listen() {
-	defer c.wsConn.Close()
-
+// Messages are handled in the order they arrive and automatically sorted based
+// on type. If the connection is lost after NewClient returned and the client
+// wasn't closed, the supervisor is told to reconnect.
+func (c *Client) listen(conn *connection) {
 	for {
-		select {
-		case <-c.reconnectChan:
-		default:
-			_, msg, err := c.wsConn.ReadMessage()
-			if err != nil {
-				c.logger.Error("error reading message: %w", err)
-				c.reconnectChan <- true
+		_, msg, err := conn.ws.ReadMessage()
+		if err != nil {
+			conn.close()
+
+			c.mu.RLock()
+			stopped := c.closed || !c.initialized
+			c.mu.RUnlock()
+
+			if stopped {
+				return
+			}
 
-				break
+			c.logger.Error("error reading message: %w", err)
+
+			select {
+			case c.lost <- lostConnection{conn: conn, err: err}:
+			case <-c.done:
 			}
 
-			go c.parseIncomingMessage(msg)
+			return
 		}
+
+		c.parseIncomingMessage(msg)
 	}
 }
 
@@ -72,25 +253,82 @@ func (c *Client) parseIncomingMessage(msg []byte) {
 	var m incomingMsg
 	if err := json.Unmarshal(msg, &m); err != nil {
 		c.logger.Error("error unmarshaling message: %w", err)
+		return
 	}
 
 	c.logger.Debug("received message: %s", string(msg))
 
 	switch m.Type {
 	case messageTypePong:
-		c.pongChan <- true
+		select {
+		case c.pongChan <- true:
+		default:
+		}
 	case messageTypeResult:
-		c.resultChan[m.ID] <- msg
+		c.deliverResult(m.ID, msg)
 	case messageTypeEvent:
-		go c.eventResponseHandler(m.ID, msg)
+		c.eventResponseHandler(m.ID, msg)
 	default:
 		c.logger.Warn("unknown message type: %s", m.Type.String())
 	}
 }
 
+// Hand a result message to the command waiting on it. Results for commands
+// that already gave up (timeout or cancelled context) are dropped.
+func (c *Client) deliverResult(id int64, msg []byte) {
+	c.mu.RLock()
+	responseChan, exists := c.resultChan[id]
+	c.mu.RUnlock()
+
+	if !exists {
+		c.logger.Debug("dropping result for request ID %d with no waiter", id)
+		return
+	}
+
+	select {
+	case responseChan <- msg:
+	default:
+	}
+}
+
 // Handle type: event messages to determine if a callback function needs to be called.
+// Events are dropped once the client is shutting down.
 func (c *Client) eventResponseHandler(id int64, msg []byte) {
-	if handler, exists := c.eventHandler[id]; exists {
+	if c.isClosed() {
+		return
+	}
+
+	c.mu.RLock()
+	handler, exists := c.eventHandler[id]
+	trigger, isTrigger := c.triggerHandler[id]
+	c.mu.RUnlock()
+
+	if isTrigger {
+		c.triggerResponseHandler(trigger, msg)
+		return
+	}
+
+	if exists && handler.compressed {
+		c.updateCompressedStates(id, handler, msg)
+		return
+	}
+
+	if exists && handler.raw != nil {
+		var response struct {
+			Event json.RawMessage `json:"event"`
+		}
+
+		if err := json.Unmarshal(msg, &response); err != nil {
+			c.logger.Error("error unmarshalling event message: %w", err)
+			return
+		}
+
+		handler.raw(response.Event)
+
+		return
+	}
+
+	if exists {
 		var response struct {
 			Event types.Event `json:"event"`
 		}
@@ -100,19 +338,39 @@ func (c *Client) eventResponseHandler(id int64, msg []byte) {
 		}
 
 		if handler.Callback != nil {
-			go handler.Callback(response.Event)
+			c.callbacks.run(func() { handler.Callback(response.Event) })
 		}
 
-		if response.Event.EventType == "state_changed" {
-			go c.updateState(response.Event.Data)
+		if handler.updatesState && response.Event.EventType == "state_changed" {
+			c.updateState(response.Event.Data)
 			return
 		}
 	}
 }
 
+// Handle subscribe_trigger event messages, which carry the trigger variables
+// instead of a regular event.
+func (c *Client) triggerResponseHandler(handler triggerHandler, msg []byte) {
+	var response struct {
+		Event struct {
+			Variables types.Trigger `json:"variables"`
+			Context   types.Context `json:"context"`
+		} `json:"event"`
+	}
+
+	if err := json.Unmarshal(msg, &response); err != nil {
+		c.logger.Error("error unmarshalling trigger message: %w", err)
+		return
+	}
+
+	if handler.Callback != nil {
+		c.callbacks.run(func() { handler.Callback(response.Event.Variables) })
+	}
+}
+
 // Starts a loop for sending and receiving ping/pong messages on the websocket.
-// If pong times out the websocket will immediately attempt to reconnect.
-func (c *Client) startHeartbeat() {
+// If pong times out the connection is closed, which makes listen reconnect.
+func (c *Client) startHeartbeat(conn *connection) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -122,54 +380,49 @@ func (c *Client) startHeartbeat() {
 	for {
 		select {
 		case <-ticker.C:
-			if err := c.sendPing(); err != nil {
+			if err := c.sendPing(conn); err != nil {
 				c.logger.Error("error sending ping: %w", err)
 			}
 
 			timeout := time.NewTimer(c.timeout)
 			select {
 			case <-c.pongChan:
+				timeout.Stop()
+
 				consecutiveTimeouts = 0
 
 				continue
+			case <-conn.done:
+				timeout.Stop()
+				return
 			case <-timeout.C:
 				consecutiveTimeouts++
 				c.logger.Warn("ping timeout #%d", consecutiveTimeouts)
 
 				if consecutiveTimeouts >= maxTimeouts {
 					c.logger.Error("ping failed after %d timeouts, reconnecting", maxTimeouts)
-					c.reconnectChan <- true
+					conn.close()
 
 					return
 				}
 			}
-		// If reconnect called, attempt to establish reconnection
-		case <-c.reconnectChan:
-			c.logger.Warn("reconnecting...")
-			c.wsConn.Close()
-			c.msgID = 1
-
-			var attempt int
-
-			for {
-				if err := c.run(); err == nil {
-					attempt++
-					c.logger.Info("reconnect failed, trying again. attempt %d", attempt)
-					time.Sleep(5 * time.Second)
-				}
-			}
+		case <-conn.done:
+			return
 		}
 	}
 }
 
-func (c *Client) sendPing() error {
+func (c *Client) sendPing(conn *connection) error {
 	msg := baseMessage{
 		Type: messageTypePing,
 	}
 	id := c.getNextID()
 	msg.SetID(id)
 
-	if err := c.wsConn.WriteJSON(&msg); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := conn.writeJSON(ctx, &msg); err != nil {
 		return fmt.Errorf("error sending ping: %w", err)
 	}
 
@@ -0,0 +1,362 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	compressedStates struct {
+		subMu     sync.Mutex                  // Serializes changes to the subscriptions
+		entityIDs entity.IDList               // Entities to subscribe to, every entity if empty. Unused when filtered
+		filtered  bool                        // Subscribe to the entities with listeners, see WithFilteredStates
+		requests  []*subscribeEntitiesRequest // The current subscriptions, only ever more than one when filtered
+
+		// Guarded by Client.mu
+		resync bool                    // Report every difference in the next first message, set when reconnecting
+		ready  map[int64]chan struct{} // Closed when the first message of a subscription arrives
+	}
+
+	subscribeEntitiesRequest struct {
+		baseMessage
+		EntityIDs entity.IDList `json:"entity_ids,omitempty"`
+	}
+
+	// A subscribe_entities message. The first one adds every entity.
+	entitiesDiff struct {
+		Added   map[string]compressedState  `json:"a"`
+		Changed map[string]compressedChange `json:"c"`
+		Removed []string                    `json:"r"`
+	}
+
+	// An entity state with short keys. In changes only the fields that changed are set.
+	compressedState struct {
+		State       *state.Value               `json:"s"`
+		Attributes  map[string]json.RawMessage `json:"a"`
+		Context     *compressedContext         `json:"c"`
+		LastChanged *types.UnixTime            `json:"lc"` // Also the last update unless lu is set
+		LastUpdated *types.UnixTime            `json:"lu"`
+	}
+
+	compressedChange struct {
+		Additions compressedState `json:"+"`
+		Removals  struct {
+			Attributes []string `json:"a"`
+		} `json:"-,"` // The trailing comma names the key "-" rather than skipping the field
+	}
+
+	// Just the context ID when there is no user or parent, otherwise the full context.
+	compressedContext types.Context
+)
+
+// WithCompressedStates keeps EntitiesMap current with subscribe_entities instead
+// of get_states and a state_changed subscription. Home Assistant then sends only
+// what changed in each state, which uses far less bandwidth and CPU on large
+// installs. If entity IDs are given only those entities are tracked, so
+// EntitiesMap and entity listeners only see them.
+func WithCompressedStates(entityIDs ...entity.ID) ClientOption {
+	return func(c *Client) {
+		c.compressed = &compressedStates{
+			entityIDs: entityIDs,
+			ready:     make(map[int64]chan struct{}),
+		}
+	}
+}
+
+// Subscribe to compressed state updates and wait for the first states, until
+// ctx is done or the client timeout elapses if ctx has no deadline. Callers
+// serialize changes to the subscriptions with subMu.
+func (c *Client) subscribeEntities(ctx context.Context, entityIDs entity.IDList) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	request := &subscribeEntitiesRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeSubscribeEntities,
+		},
+		EntityIDs: entityIDs,
+	}
+
+	handler := eventHandler{EventType: "subscribe_entities", compressed: true, request: request}
+	ready := make(chan struct{})
+
+	if err := c.write(ctx, request, nil, registerHandler(func(id int64) {
+		c.eventHandler[id] = handler
+		c.compressed.ready[id] = ready
+	}, func(id int64) {
+		delete(c.eventHandler, id)
+		delete(c.compressed.ready, id)
+	})); err != nil {
+		return err
+	}
+
+	c.compressed.requests = append(c.compressed.requests, request)
+
+	select {
+	case <-ready:
+		c.logger.Info("states retrieved")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for initial states: %w", ctx.Err())
+	}
+}
+
+// Forget the subscribe_entities subscriptions of a lost connection so that
+// syncing states makes them again rather than resubscribe replaying them. The
+// first message of the next subscription reports every difference.
+func (c *Client) dropCompressedSubscriptions() {
+	cs := c.compressed
+
+	cs.subMu.Lock()
+	subscribed := len(cs.requests) > 0
+	cs.requests = nil
+	cs.subMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, handler := range c.eventHandler {
+		if handler.compressed {
+			delete(c.eventHandler, id)
+			delete(cs.ready, id)
+		}
+	}
+
+	cs.resync = subscribed
+}
+
+// Apply a subscribe_entities message to EntitiesMap and run the listeners for
+// every entity that changed. The first message of a subscription holds every
+// entity it covers and replaces their states, or all of EntitiesMap if it
+// covers every entity.
+func (c *Client) updateCompressedStates(id int64, handler eventHandler, msg []byte) {
+	var response struct {
+		Event entitiesDiff `json:"event"`
+	}
+
+	if err := json.Unmarshal(msg, &response); err != nil {
+		c.logger.Error("error unmarshalling entities message: %w", err)
+		return
+	}
+
+	diff := response.Event
+	added := make(types.EntitiesMap, len(diff.Added))
+
+	for entityID, s := range diff.Added {
+		if e, ok := s.entity(entityID, c); ok {
+			added[e.EntityID] = e
+		}
+	}
+
+	c.mu.Lock()
+	if ready, first := c.compressed.ready[id]; first {
+		close(ready)
+		delete(c.compressed.ready, id)
+
+		resync := c.compressed.resync
+		c.compressed.resync = false
+
+		previous := c.EntitiesMap
+		current := added
+
+		if requested := handler.request.(*subscribeEntitiesRequest).EntityIDs; len(requested) == 0 {
+			c.EntitiesMap = added
+		} else {
+			previous = make(types.EntitiesMap, len(requested))
+			current = make(types.EntitiesMap, len(requested))
+
+			for _, entityID := range requested {
+				if e, exists := c.EntitiesMap[entityID]; exists {
+					previous[entityID] = e
+				}
+
+				if e, exists := added[entityID]; exists {
+					current[entityID] = e
+					c.EntitiesMap[entityID] = e
+				} else {
+					delete(c.EntitiesMap, entityID)
+				}
+			}
+		}
+		c.mu.Unlock()
+
+		for _, change := range stateChanges(previous, current) {
+			// Entities that only joined the subscription did not change
+			if resync || change.OldState != nil {
+				c.dispatchStateChange(change)
+			}
+		}
+
+		return
+	}
+
+	var changes []*types.StateChange
+
+	for entityID, newState := range added {
+		change := &types.StateChange{EntityID: entityID, NewState: &newState}
+		if oldState, exists := c.EntitiesMap[entityID]; exists {
+			change.OldState = &oldState
+		}
+
+		c.EntitiesMap[entityID] = newState
+		changes = append(changes, change)
+	}
+
+	for id, change := range diff.Changed {
+		entityID, err := entity.Parse(id)
+		if err != nil {
+			c.logger.Error("error parsing entity ID %s: %w", id, err)
+			continue
+		}
+
+		oldState, exists := c.EntitiesMap[entityID]
+		if !exists {
+			c.logger.Warn("received change for unknown entity %s", id)
+			continue
+		}
+
+		newState, err := change.apply(oldState)
+		if err != nil {
+			c.logger.Error("error applying change to %s: %w", id, err)
+			continue
+		}
+
+		c.EntitiesMap[entityID] = newState
+		changes = append(changes, &types.StateChange{EntityID: entityID, OldState: &oldState, NewState: &newState})
+	}
+
+	for _, id := range diff.Removed {
+		entityID, err := entity.Parse(id)
+		if err != nil {
+			c.logger.Error("error parsing entity ID %s: %w", id, err)
+			continue
+		}
+
+		oldState, exists := c.EntitiesMap[entityID]
+		if !exists {
+			continue
+		}
+
+		delete(c.EntitiesMap, entityID)
+		changes = append(changes, &types.StateChange{EntityID: entityID, OldState: &oldState})
+	}
+	c.mu.Unlock()
+
+	for _, change := range changes {
+		c.dispatchStateChange(change)
+	}
+}
+
+// Expand an added entity, logging entities that can't be decoded.
+func (s compressedState) entity(id string, c *Client) (types.Entity, bool) {
+	entityID, err := entity.Parse(id)
+	if err != nil {
+		c.logger.Error("error parsing entity ID %s: %w", id, err)
+		return types.Entity{}, false
+	}
+
+	e := types.Entity{EntityID: entityID}
+
+	if s.State != nil {
+		e.State = *s.State
+	}
+
+	if s.Context != nil {
+		e.Context = types.Context(*s.Context)
+	}
+
+	if s.LastChanged != nil {
+		e.LastChanged = s.LastChanged.Time().UTC()
+		e.LastUpdated = e.LastChanged
+	}
+
+	if s.LastUpdated != nil {
+		e.LastUpdated = s.LastUpdated.Time().UTC()
+	}
+
+	e.LastReported = e.LastUpdated
+
+	attributes := s.Attributes
+	if attributes == nil {
+		attributes = map[string]json.RawMessage{}
+	}
+
+	if e.Attributes, err = json.Marshal(attributes); err != nil {
+		c.logger.Error("error encoding attributes of %s: %w", id, err)
+		return types.Entity{}, false
+	}
+
+	return e, true
+}
+
+// Return the state after a change. A new last_changed also sets last_updated.
+func (change compressedChange) apply(e types.Entity) (types.Entity, error) {
+	add := change.Additions
+
+	if add.State != nil {
+		e.State = *add.State
+	}
+
+	if add.Context != nil {
+		e.Context = types.Context(*add.Context)
+	}
+
+	if add.LastChanged != nil {
+		e.LastChanged = add.LastChanged.Time().UTC()
+		e.LastUpdated = e.LastChanged
+	} else if add.LastUpdated != nil {
+		e.LastUpdated = add.LastUpdated.Time().UTC()
+	}
+
+	e.LastReported = e.LastUpdated
+
+	if len(add.Attributes) == 0 && len(change.Removals.Attributes) == 0 {
+		return e, nil
+	}
+
+	attributes := make(map[string]json.RawMessage)
+	if len(e.Attributes) > 0 {
+		if err := json.Unmarshal(e.Attributes, &attributes); err != nil {
+			return e, err
+		}
+	}
+
+	maps.Copy(attributes, add.Attributes)
+
+	for _, key := range change.Removals.Attributes {
+		delete(attributes, key)
+	}
+
+	raw, err := json.Marshal(attributes)
+	if err != nil {
+		return e, err
+	}
+
+	e.Attributes = raw
+
+	return e, nil
+}
+
+func (cc *compressedContext) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*cc = compressedContext{ID: id}
+		return nil
+	}
+
+	var context types.Context
+	if err := json.Unmarshal(data, &context); err != nil {
+		return fmt.Errorf("invalid compressed context: %w", err)
+	}
+
+	*cc = compressedContext(context)
+
+	return nil
+}
@@ -6,4 +6,10 @@ var (
 	ErrNotMinimumVersion = errors.New("home assistant is not minimum version")
 
 	ErrUnhealthyAPI = errors.New("api is not healthy")
+
+	ErrConnectionClosed = errors.New("websocket connection is closed")
+
+	ErrReconnectFailed = errors.New("failed to reconnect to home assistant")
+
+	ErrClientClosed = errors.New("client is closed")
 )
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+)
+
+// ErrRegexListenerFiltered is returned by AddRegexEntityListener when the
+// client was created with WithFilteredStates, as a pattern can't be sent to
+// Home Assistant as a list of entities.
+var ErrRegexListenerFiltered = errors.New("regex entity listeners are not supported with filtered states")
+
+// WithFilteredStates only streams the states of entities that have listeners.
+// Instead of receiving every state change in the house and filtering locally,
+// the client subscribes with subscribe_entities to the entities passed to
+// AddEntityListener and AddEntitiesListener. Each listener for entities that
+// aren't streamed yet adds a subscription for just those, and subscriptions
+// are cancelled once none of their entities have listeners. EntitiesMap only
+// holds the entities of the current subscriptions. After reconnecting they are
+// replaced by a single subscription for every entity with listeners.
+func WithFilteredStates() ClientOption {
+	return func(c *Client) {
+		c.compressed = &compressedStates{
+			filtered: true,
+			ready:    make(map[int64]chan struct{}),
+		}
+	}
+}
+
+// Cancel the subscriptions whose entities no longer have listeners, then
+// subscribe to the entities with listeners that aren't streamed yet.
+func (c *Client) updateFilteredStates(ctx context.Context) error {
+	cs := c.compressed
+
+	cs.subMu.Lock()
+	defer cs.subMu.Unlock()
+
+	c.mu.RLock()
+	listened := make(map[entity.ID]bool, len(c.entityListeners))
+	for entityID := range c.entityListeners {
+		listened[entityID] = true
+	}
+	c.mu.RUnlock()
+
+	streamed := make(map[entity.ID]bool, len(listened))
+	requests := make([]*subscribeEntitiesRequest, 0, len(cs.requests))
+
+	for _, request := range cs.requests {
+		if slices.ContainsFunc(request.EntityIDs, func(entityID entity.ID) bool { return listened[entityID] }) {
+			requests = append(requests, request)
+
+			for _, entityID := range request.EntityIDs {
+				streamed[entityID] = true
+			}
+
+			continue
+		}
+
+		_ = c.unsubscribeEvents(ctx, request, func(id int64) {
+			delete(c.eventHandler, id)
+			delete(cs.ready, id)
+		})
+	}
+
+	cs.requests = requests
+
+	var missing entity.IDList
+
+	for entityID := range listened {
+		if !streamed[entityID] {
+			missing = append(missing, entityID)
+			streamed[entityID] = true
+		}
+	}
+
+	c.mu.Lock()
+	for entityID := range c.EntitiesMap {
+		if !streamed[entityID] {
+			delete(c.EntitiesMap, entityID)
+		}
+	}
+
+	if len(missing) == 0 {
+		cs.resync = false
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(missing, func(a, b entity.ID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	return c.subscribeEntities(ctx, missing)
+}
+
+// Register an entity listener, then subscribe to its entities to check that
+// they exist.
+func (c *Client) addFilteredEntitiesListener(
+	ctx context.Context,
+	entityIDs []entity.ID,
+	listener entityListener,
+) (*Subscription, error) {
+	c.mu.Lock()
+	listener.id = c.nextListenerID()
+	for _, entityID := range entityIDs {
+		c.entityListeners[entityID] = append(c.entityListeners[entityID], listener)
+	}
+	c.mu.Unlock()
+
+	err := c.updateFilteredStates(ctx)
+	if err == nil {
+		c.mu.RLock()
+		for _, entityID := range entityIDs {
+			if err = c.EntitiesMap.Exists(entityID); err != nil {
+				break
+			}
+		}
+		c.mu.RUnlock()
+	}
+
+	if err != nil {
+		c.removeEntityListener(entityIDs, listener.id)
+
+		// Clean up even if ctx is what failed
+		if err := c.updateFilteredStates(context.WithoutCancel(ctx)); err != nil {
+			c.logger.Error("failed to update filtered states: %w", err)
+		}
+
+		return nil, err
+	}
+
+	c.logger.Debug("added filtered entity listener for %v", entityIDs)
+
+	return newSubscription(func(ctx context.Context) error {
+		c.removeEntityListener(entityIDs, listener.id)
+
+		return c.updateFilteredStates(ctx)
+	}), nil
+}
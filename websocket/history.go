@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	// HistoryOptions change which states are returned. The zero value matches
+	// the defaults of Home Assistant.
+	HistoryOptions struct {
+		EndTime          time.Time // Defaults to now, streams continue with live states if unset
+		NoStartTimeState bool      // Leave out the state each entity had at the start time
+		AllChanges       bool      // Include attribute only changes, not just significant changes
+		MinimalResponse  bool      // Only send attributes for the first state of each entity
+		NoAttributes     bool
+	}
+
+	// LogbookOptions limit a logbook stream. Without entity or device IDs
+	// every event is included.
+	LogbookOptions struct {
+		EndTime   time.Time // Streams continue with live events if unset
+		EntityIDs entity.IDList
+		DeviceIDs []string
+	}
+
+	historyRequest struct {
+		baseMessage
+		StartTime              string        `json:"start_time"`
+		EndTime                string        `json:"end_time,omitempty"`
+		EntityIDs              entity.IDList `json:"entity_ids"`
+		IncludeStartTimeState  bool          `json:"include_start_time_state"`
+		SignificantChangesOnly bool          `json:"significant_changes_only"`
+		MinimalResponse        bool          `json:"minimal_response"`
+		NoAttributes           bool          `json:"no_attributes"`
+	}
+
+	logbookStreamRequest struct {
+		baseMessage
+		StartTime string        `json:"start_time"`
+		EndTime   string        `json:"end_time,omitempty"`
+		EntityIDs entity.IDList `json:"entity_ids,omitempty"`
+		DeviceIDs []string      `json:"device_ids,omitempty"`
+	}
+)
+
+// Python only parses up to microseconds.
+const isoTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(isoTimeLayout)
+}
+
+func newHistoryRequest(
+	msgType messageType,
+	start time.Time,
+	entityIDs entity.IDList,
+	opts HistoryOptions,
+) *historyRequest {
+	return &historyRequest{
+		baseMessage: baseMessage{
+			Type: msgType,
+		},
+		StartTime:              formatTime(start),
+		EndTime:                formatTime(opts.EndTime),
+		EntityIDs:              entityIDs,
+		IncludeStartTimeState:  !opts.NoStartTimeState,
+		SignificantChangesOnly: !opts.AllChanges,
+		MinimalResponse:        opts.MinimalResponse,
+		NoAttributes:           opts.NoAttributes,
+	}
+}
+
+// HistoryDuringPeriod returns the states of entities from start until the end
+// time in opts.
+func (c *Client) HistoryDuringPeriod(
+	ctx context.Context,
+	start time.Time,
+	entityIDs entity.IDList,
+	opts HistoryOptions,
+) (types.History, error) {
+	request := newHistoryRequest(messageTypeHistoryDuringPeriod, start, entityIDs, opts)
+
+	var history types.History
+	if err := c.write(ctx, request, &history); err != nil {
+		c.logger.Error("failed to get history: %w", err)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// StreamHistory calls f with the states of entities since start, then with new
+// states as they are recorded until the end time in opts, if there is one.
+// After a reconnect the stream starts over from start.
+func (c *Client) StreamHistory(
+	ctx context.Context,
+	start time.Time,
+	entityIDs entity.IDList,
+	opts HistoryOptions,
+	f func(types.HistoryStream),
+) (*Subscription, error) {
+	request := newHistoryRequest(messageTypeHistoryStream, start, entityIDs, opts)
+
+	return c.subscribeStream(ctx, request, "history/stream", func(event json.RawMessage) {
+		var stream types.HistoryStream
+		if err := json.Unmarshal(event, &stream); err != nil {
+			c.logger.Error("error unmarshalling history stream: %w", err)
+			return
+		}
+
+		f(stream)
+	})
+}
+
+// StreamLogbook calls f with the logbook events since start, then with new
+// events as they happen until the end time in opts, if there is one. After a
+// reconnect the stream starts over from start.
+func (c *Client) StreamLogbook(
+	ctx context.Context,
+	start time.Time,
+	opts LogbookOptions,
+	f func(types.LogbookStream),
+) (*Subscription, error) {
+	request := &logbookStreamRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeLogbookEventStream,
+		},
+		StartTime: formatTime(start),
+		EndTime:   formatTime(opts.EndTime),
+		EntityIDs: opts.EntityIDs,
+		DeviceIDs: opts.DeviceIDs,
+	}
+
+	return c.subscribeStream(ctx, request, "logbook/event_stream", func(event json.RawMessage) {
+		var stream types.LogbookStream
+		if err := json.Unmarshal(event, &stream); err != nil {
+			c.logger.Error("error unmarshalling logbook stream: %w", err)
+			return
+		}
+
+		f(stream)
+	})
+}
@@ -0,0 +1,250 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/constants/config"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Tracks whether Home Assistant is running, from get_config on every connect
+// and the homeassistant_started and homeassistant_stop events in between.
+type lifecycle struct {
+	wait     bool       // Set by WithWaitForRunning
+	mu       sync.Mutex // Guards everything below
+	tracking bool
+	running  bool
+	hookID   int64
+	started  map[int64]func()
+	stopping map[int64]func()
+	held     map[entity.ID]*types.Entity // State before the first change held back per entity
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		started:  make(map[int64]func()),
+		stopping: make(map[int64]func()),
+		held:     make(map[entity.ID]*types.Entity),
+	}
+}
+
+// WithWaitForRunning holds back entity listeners while Home Assistant is not
+// running, such as while it is still loading integrations. States are still
+// kept current, and once it is running listeners are called once for every
+// entity that changed in the meantime.
+func WithWaitForRunning() ClientOption {
+	return func(c *Client) {
+		c.lifecycle.wait = true
+	}
+}
+
+// OnHomeAssistantStarted calls f every time Home Assistant finishes starting,
+// and right away if it is already running.
+func (c *Client) OnHomeAssistantStarted(ctx context.Context, f func()) (*Subscription, error) {
+	return c.addLifecycleHook(ctx, true, f)
+}
+
+// OnHomeAssistantStopping calls f every time Home Assistant begins to shut down.
+func (c *Client) OnHomeAssistantStopping(ctx context.Context, f func()) (*Subscription, error) {
+	return c.addLifecycleHook(ctx, false, f)
+}
+
+// Running reports whether Home Assistant was running the last time the client
+// checked. Always false until WithWaitForRunning or a lifecycle hook starts
+// the tracking.
+func (c *Client) Running() bool {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+
+	return c.lifecycle.running
+}
+
+func (c *Client) addLifecycleHook(ctx context.Context, started bool, f func()) (*Subscription, error) {
+	l := c.lifecycle
+
+	l.mu.Lock()
+	l.hookID++
+	id := l.hookID
+
+	hooks := l.stopping
+	if started {
+		hooks = l.started
+	}
+
+	hooks[id] = f
+	tracking := l.tracking
+	running := l.running
+	l.tracking = true
+	l.mu.Unlock()
+
+	remove := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(hooks, id)
+	}
+
+	if !tracking {
+		// Hooks are called by the first update if Home Assistant is running
+		if err := c.trackLifecycle(ctx); err != nil {
+			remove()
+			return nil, err
+		}
+	} else if started && running {
+		c.callbacks.run(f)
+	}
+
+	return newSubscription(func(context.Context) error {
+		remove()
+		return nil
+	}), nil
+}
+
+// Subscribe to the start and stop events, then check the current state. The
+// subscriptions are replayed on reconnect like any other.
+func (c *Client) trackLifecycle(ctx context.Context) error {
+	c.lifecycle.mu.Lock()
+	c.lifecycle.tracking = true
+	c.lifecycle.mu.Unlock()
+
+	events := []struct {
+		eventType string
+		running   bool
+	}{
+		{"homeassistant_started", true},
+		{"homeassistant_stop", false},
+	}
+
+	subscribed := make([]*subscribeToEventRequest, 0, len(events))
+
+	for _, event := range events {
+		request := &subscribeToEventRequest{
+			baseMessage: baseMessage{
+				Type: messageTypeSubscribeEvent,
+			},
+			EventType: event.eventType,
+		}
+
+		if err := c.subscribeToEvent(ctx, request, eventHandler{
+			EventType: event.eventType,
+			Callback: func(types.Event) {
+				c.setRunning(event.running)
+			},
+		}); err != nil {
+			c.logger.Error("failed to subscribe to %s: %w", event.eventType, err)
+
+			// Don't leave a handler behind for the next attempt to duplicate
+			for _, request := range subscribed {
+				_ = c.unsubscribeEvents(context.WithoutCancel(ctx), request, func(id int64) {
+					delete(c.eventHandler, id)
+				})
+			}
+
+			c.lifecycle.mu.Lock()
+			c.lifecycle.tracking = false
+			c.lifecycle.mu.Unlock()
+
+			return err
+		}
+
+		subscribed = append(subscribed, request)
+	}
+
+	return c.refreshLifecycle(ctx)
+}
+
+// Update the running state from get_config.
+func (c *Client) refreshLifecycle(ctx context.Context) error {
+	cfg, err := c.GetConfigContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.setRunning(cfg.State == config.StateRunning)
+
+	return nil
+}
+
+// Record a change of the running state, calling the hooks and releasing held
+// back state changes.
+func (c *Client) setRunning(running bool) {
+	l := c.lifecycle
+
+	l.mu.Lock()
+	if l.running == running {
+		l.mu.Unlock()
+		return
+	}
+
+	l.running = running
+
+	hooks := l.stopping
+	if running {
+		hooks = l.started
+	}
+
+	funcs := make([]func(), 0, len(hooks))
+	for _, f := range hooks {
+		funcs = append(funcs, f)
+	}
+
+	held := l.held
+	l.held = make(map[entity.ID]*types.Entity)
+	l.mu.Unlock()
+
+	if running {
+		c.logger.Info("home assistant is running")
+		c.releaseStateChanges(held)
+	} else {
+		c.logger.Info("home assistant is not running")
+	}
+
+	for _, f := range funcs {
+		c.callbacks.run(f)
+	}
+}
+
+// Report whether a state change must be held back because Home Assistant is
+// not running. Only the state before the first held back change of each
+// entity is kept.
+func (c *Client) holdStateChange(change *types.StateChange) bool {
+	l := c.lifecycle
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.wait || l.running {
+		return false
+	}
+
+	if _, exists := l.held[change.EntityID]; !exists {
+		l.held[change.EntityID] = change.OldState
+	}
+
+	return true
+}
+
+// Dispatch one state change for every held back entity that ended up changed.
+func (c *Client) releaseStateChanges(held map[entity.ID]*types.Entity) {
+	for entityID, oldState := range held {
+		c.mu.RLock()
+		newState, exists := c.EntitiesMap[entityID]
+		c.mu.RUnlock()
+
+		change := &types.StateChange{EntityID: entityID, OldState: oldState}
+		if exists {
+			change.NewState = &newState
+		}
+
+		switch {
+		case oldState == nil && !exists:
+			continue
+		case oldState != nil && exists && oldState.LastUpdated.Equal(newState.LastUpdated):
+			continue
+		}
+
+		c.dispatchStateChange(change)
+	}
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -59,11 +60,47 @@ func IgnoreStatesEqual() FilterOption {
 	}
 }
 
-func (c *Client) AddEntityListener(entityID entity.ID, f func(*types.StateChange), opts ...FilterOption) error {
-	if err := c.EntitiesMap.Exists(entityID); err != nil {
-		return err
-	}
+// AddEntityListener calls f for every state change of an entity. With
+// WithFilteredStates this waits for Home Assistant to stream the entity if it
+// isn't yet, see AddEntityListenerContext.
+func (c *Client) AddEntityListener(
+	entityID entity.ID,
+	f func(*types.StateChange),
+	opts ...FilterOption,
+) (*Subscription, error) {
+	return c.AddEntitiesListenerContext(context.Background(), []entity.ID{entityID}, f, opts...)
+}
+
+// AddEntityListenerContext is like AddEntityListener but uses the provided
+// context while subscribing to the entity.
+func (c *Client) AddEntityListenerContext(
+	ctx context.Context,
+	entityID entity.ID,
+	f func(*types.StateChange),
+	opts ...FilterOption,
+) (*Subscription, error) {
+	return c.AddEntitiesListenerContext(ctx, []entity.ID{entityID}, f, opts...)
+}
+
+// AddEntitiesListener calls f for every state change of any of the entities.
+// With WithFilteredStates this waits for Home Assistant to stream the entities
+// that aren't yet, see AddEntitiesListenerContext.
+func (c *Client) AddEntitiesListener(
+	entityIDs []entity.ID,
+	f func(*types.StateChange),
+	opts ...FilterOption,
+) (*Subscription, error) {
+	return c.AddEntitiesListenerContext(context.Background(), entityIDs, f, opts...)
+}
 
+// AddEntitiesListenerContext is like AddEntitiesListener but uses the provided
+// context while subscribing to the entities.
+func (c *Client) AddEntitiesListenerContext(
+	ctx context.Context,
+	entityIDs []entity.ID,
+	f func(*types.StateChange),
+	opts ...FilterOption,
+) (*Subscription, error) {
 	filters := &filterOptions{}
 	for _, option := range opts {
 		option(filters)
@@ -74,40 +111,60 @@ func (c *Client) AddEntityListener(entityID entity.ID, f func(*types.StateChange
 		FilterOptions: *filters,
 	}
 
-	c.entityListeners[entityID] = append(c.entityListeners[entityID], listener)
-	c.logger.Debug("added entity listener for %s", entityID)
-
-	return nil
-}
-
-func (c *Client) AddEntitiesListener(entityIDs []entity.ID, f func(*types.StateChange), opts ...FilterOption) error {
-	filters := &filterOptions{}
-	for _, option := range opts {
-		option(filters)
+	if c.compressed != nil && c.compressed.filtered {
+		return c.addFilteredEntitiesListener(ctx, entityIDs, listener)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for _, entityID := range entityIDs {
 		if err := c.EntitiesMap.Exists(entityID); err != nil {
-			return err
+			return nil, err
 		}
+	}
 
-		listener := entityListener{
-			callback:      f,
-			FilterOptions: *filters,
-		}
+	listener.id = c.nextListenerID()
 
+	for _, entityID := range entityIDs {
 		c.entityListeners[entityID] = append(c.entityListeners[entityID], listener)
 		c.logger.Debug("added entity listener for %s", entityID)
 	}
 
-	return nil
+	return newSubscription(func(context.Context) error {
+		c.removeEntityListener(entityIDs, listener.id)
+
+		return nil
+	}), nil
+}
+
+func (c *Client) removeEntityListener(entityIDs []entity.ID, id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entityID := range entityIDs {
+		c.entityListeners[entityID] = removeListener(c.entityListeners[entityID], id)
+		if len(c.entityListeners[entityID]) == 0 {
+			delete(c.entityListeners, entityID)
+		}
+
+		c.logger.Debug("removed entity listener for %s", entityID)
+	}
 }
 
 // Call a function whenever an entity event happens that matches your regex pattern
-func (c *Client) AddRegexEntityListener(regexPattern string, f func(*types.StateChange), opts ...FilterOption) error {
+func (c *Client) AddRegexEntityListener(
+	regexPattern string,
+	f func(*types.StateChange),
+	opts ...FilterOption,
+) (*Subscription, error) {
+	if c.compressed != nil && c.compressed.filtered {
+		return nil, ErrRegexListenerFiltered
+	}
+
 	pattern, err := regexp.Compile(regexPattern)
 	if err != nil {
-		return fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
 	}
 
 	filters := &filterOptions{}
@@ -115,7 +172,11 @@ func (c *Client) AddRegexEntityListener(regexPattern string, f func(*types.State
 		option(filters)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	listener := entityListener{
+		id:            c.nextListenerID(),
 		callback:      f,
 		FilterOptions: *filters,
 	}
@@ -123,10 +184,45 @@ func (c *Client) AddRegexEntityListener(regexPattern string, f func(*types.State
 	c.regexEntityListeners[pattern] = append(c.regexEntityListeners[pattern], listener)
 	c.logger.Debug("added regex entity listener pattern %s", regexPattern)
 
-	return nil
+	return newSubscription(func(context.Context) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.regexEntityListeners[pattern] = removeListener(c.regexEntityListeners[pattern], listener.id)
+		if len(c.regexEntityListeners[pattern]) == 0 {
+			delete(c.regexEntityListeners, pattern)
+		}
+
+		c.logger.Debug("removed regex entity listener pattern %s", regexPattern)
+
+		return nil
+	}), nil
+}
+
+// Increment the ID used to identify local listeners. Must be called with c.mu held.
+func (c *Client) nextListenerID() int64 {
+	c.listenerID++
+
+	return c.listenerID
+}
+
+// Return the listeners without the one matching id.
+func removeListener(listeners []entityListener, id int64) []entityListener {
+	remaining := make([]entityListener, 0, len(listeners))
+
+	for _, listener := range listeners {
+		if listener.id != id {
+			remaining = append(remaining, listener)
+		}
+	}
+
+	return remaining
 }
 
 func (c *Client) AddDateTimeEntityTrigger(entityID entity.ID, callback func()) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if err := c.EntitiesMap.Exists(entityID); err != nil {
 		return err
 	}
@@ -143,26 +239,51 @@ func (c *Client) updateState(input []byte) {
 	}
 
 	c.mu.Lock()
-	c.EntitiesMap[msg.EntityID] = *msg.NewState
+	if msg.NewState == nil {
+		delete(c.EntitiesMap, msg.EntityID)
+	} else {
+		c.EntitiesMap[msg.EntityID] = *msg.NewState
+	}
 	c.mu.Unlock()
 
-	go c.entityIDCallbackTrigger(&msg)
-	go c.regexCallbackTrigger(&msg)
-	go c.checkDateTimeEntity(&msg)
+	c.dispatchStateChange(&msg)
+}
+
+// Run every listener interested in the state change.
+func (c *Client) dispatchStateChange(msg *types.StateChange) {
+	if c.holdStateChange(msg) {
+		return
+	}
+
+	c.callbacks.run(func() { c.entityIDCallbackTrigger(msg) })
+	c.callbacks.run(func() { c.regexCallbackTrigger(msg) })
+	c.callbacks.run(func() { c.checkDateTimeEntity(msg) })
 }
 
 func (c *Client) entityIDCallbackTrigger(msg *types.StateChange) {
-	if entityListeners, exists := c.entityListeners[msg.EntityID]; exists {
-		go c.triggerCallback(msg, entityListeners...)
+	c.mu.RLock()
+	entityListeners, exists := c.entityListeners[msg.EntityID]
+	c.mu.RUnlock()
+
+	if exists {
+		c.callbacks.run(func() { c.triggerCallback(msg, entityListeners...) })
 	}
 }
 
 func (c *Client) regexCallbackTrigger(msg *types.StateChange) {
+	var matched []entityListener
+
+	c.mu.RLock()
 	for pattern, entityListeners := range c.regexEntityListeners {
 		if pattern.MatchString(msg.EntityID.String()) {
-			go c.triggerCallback(msg, entityListeners...)
+			matched = append(matched, entityListeners...)
 		}
 	}
+	c.mu.RUnlock()
+
+	if len(matched) > 0 {
+		c.callbacks.run(func() { c.triggerCallback(msg, matched...) })
+	}
 }
 
 // If the state of a datetime entity used for a trigger is changed, this updates it.
@@ -193,12 +314,12 @@ func (c *Client) checkDateTimeEntity(msg *types.StateChange) {
 
 func (c *Client) triggerCallback(msg *types.StateChange, els ...entityListener) {
 	for _, el := range els {
-		go func(el entityListener) {
+		c.callbacks.run(func() {
 			if shouldTriggerListener(msg, el.FilterOptions) {
 				el.callback(msg)
 				c.logger.Debug("triggered entity callback function for %s: %v", msg.EntityID, msg)
 			}
-		}(el)
+		})
 	}
 }
 
@@ -207,23 +328,24 @@ func shouldTriggerListener(state *types.StateChange, opts filterOptions) bool {
 		return false
 	}
 
-	if opts.IgnorePreviousUnknown && state.OldState.State.IsUnknown() {
+	if opts.IgnorePreviousUnknown && state.OldState != nil && state.OldState.State.IsUnknown() {
 		return false
 	}
 
-	if opts.IgnorePreviousUnavail && state.OldState.State.IsUnavailable() {
+	if opts.IgnorePreviousUnavail && state.OldState != nil && state.OldState.State.IsUnavailable() {
 		return false
 	}
 
-	if opts.IgnoreUnknown && state.NewState.State.IsUnknown() {
+	if opts.IgnoreUnknown && state.NewState != nil && state.NewState.State.IsUnknown() {
 		return false
 	}
 
-	if opts.IgnoreUnavailable && state.NewState.State.IsUnavailable() {
+	if opts.IgnoreUnavailable && state.NewState != nil && state.NewState.State.IsUnavailable() {
 		return false
 	}
 
-	if opts.IgnoreCurrentEqualsPrev && state.OldState.State == state.NewState.State {
+	if opts.IgnoreCurrentEqualsPrev && state.OldState != nil && state.NewState != nil &&
+		state.OldState.State == state.NewState.State {
 		return false
 	}
 
@@ -7,6 +7,7 @@ import (
 type (
 	cmdMessage interface {
 		SetID(id int64)
+		GetID() int64
 	}
 	baseMessage struct {
 		ID   int64       `json:"id"`
@@ -18,6 +19,10 @@ func (b *baseMessage) SetID(id int64) {
 	b.ID = id
 }
 
+func (b *baseMessage) GetID() int64 {
+	return b.ID
+}
+
 type messageType string
 
 func (mt messageType) String() string {
@@ -60,7 +65,46 @@ const (
 	messageTypeGetPanels         messageType = "get_panels"
 	messageTypeGetServices       messageType = "get_services"
 	messageTypeGetStates         messageType = "get_states"
+	messageTypeSubscribeEntities messageType = "subscribe_entities"
+	messageTypeRenderTemplate    messageType = "render_template"
 	messageTypeValidateConfig    messageType = "validate_config"
+	messageTypeExecuteScript     messageType = "execute_script"
+
+	messageTypeHistoryDuringPeriod messageType = "history/history_during_period"
+	messageTypeHistoryStream       messageType = "history/stream"
+	messageTypeLogbookEventStream  messageType = "logbook/event_stream"
+
+	messageTypeStatisticsDuringPeriod messageType = "recorder/statistics_during_period"
+	messageTypeListStatisticIDs       messageType = "recorder/list_statistic_ids"
+	messageTypeGetStatisticsMetadata  messageType = "recorder/get_statistics_metadata"
+	messageTypeImportStatistics       messageType = "recorder/import_statistics"
+)
+
+// Registries
+const (
+	messageTypeEntityRegistryList   messageType = "config/entity_registry/list"
+	messageTypeEntityRegistryGet    messageType = "config/entity_registry/get"
+	messageTypeEntityRegistryUpdate messageType = "config/entity_registry/update"
+	messageTypeEntityRegistryRemove messageType = "config/entity_registry/remove"
+
+	messageTypeDeviceRegistryList              messageType = "config/device_registry/list"
+	messageTypeDeviceRegistryUpdate            messageType = "config/device_registry/update"
+	messageTypeDeviceRegistryRemoveConfigEntry messageType = "config/device_registry/remove_config_entry"
+
+	messageTypeAreaRegistryList   messageType = "config/area_registry/list"
+	messageTypeAreaRegistryCreate messageType = "config/area_registry/create"
+	messageTypeAreaRegistryUpdate messageType = "config/area_registry/update"
+	messageTypeAreaRegistryDelete messageType = "config/area_registry/delete"
+
+	messageTypeFloorRegistryList   messageType = "config/floor_registry/list"
+	messageTypeFloorRegistryCreate messageType = "config/floor_registry/create"
+	messageTypeFloorRegistryUpdate messageType = "config/floor_registry/update"
+	messageTypeFloorRegistryDelete messageType = "config/floor_registry/delete"
+
+	messageTypeLabelRegistryList   messageType = "config/label_registry/list"
+	messageTypeLabelRegistryCreate messageType = "config/label_registry/create"
+	messageTypeLabelRegistryUpdate messageType = "config/label_registry/update"
+	messageTypeLabelRegistryDelete messageType = "config/label_registry/delete"
 )
 
 // Ping/Pong
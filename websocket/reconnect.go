@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+type (
+	// ConnectionState is the state of the connection to Home Assistant.
+	ConnectionState int
+
+	// Backoff sets the delay between reconnect attempts. The delay starts at
+	// Initial and is multiplied by Multiplier after every failed attempt, up
+	// to Max. Each delay is moved randomly by up to Jitter times itself so
+	// that clients restarted together don't reconnect in lockstep.
+	Backoff struct {
+		Initial     time.Duration
+		Max         time.Duration
+		Multiplier  float64
+		Jitter      float64 // Between 0 and 1
+		MaxAttempts int     // 0 keeps trying until the client is closed
+	}
+)
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateAuthenticating
+	StateConnected // Authenticated and done resubscribing after a reconnect
+)
+
+// DefaultBackoff is used unless WithReconnectBackoff is given.
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateAuthenticating:
+		return "authenticating"
+	case StateConnected:
+		return "connected"
+	default:
+		return fmt.Sprintf("ConnectionState(%d)", int(s))
+	}
+}
+
+// WithReconnectBackoff changes how the client retries after losing the
+// connection. The first connection made by NewClient is never retried.
+func WithReconnectBackoff(backoff Backoff) ClientOption {
+	return func(c *Client) {
+		c.backoff = backoff
+	}
+}
+
+// WithConnectionStateHandler calls f every time the connection state changes,
+// starting with the first connection attempt in NewClient. Calls are made in
+// order from a separate goroutine. err is the reason for StateDisconnected,
+// nil after Close, and wraps ErrReconnectFailed once the client gives up.
+func WithConnectionStateHandler(f func(state ConnectionState, err error)) ClientOption {
+	return func(c *Client) {
+		c.stateHandler = f
+	}
+}
+
+// ConnectionState returns the current state of the connection.
+func (c *Client) ConnectionState() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.state
+}
+
+func (c *Client) setConnectionState(state ConnectionState, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == state {
+		return
+	}
+
+	c.state = state
+
+	if c.stateHandler != nil {
+		c.stateQueue.push(func() { c.stateHandler(state, err) })
+	}
+}
+
+// Reconnect every time listen reports a lost connection, until the client is
+// closed. Reports about connections that were already replaced are ignored.
+func (c *Client) supervise() {
+	for {
+		select {
+		case lost := <-c.lost:
+			if lost.conn != c.currentConn() {
+				continue
+			}
+
+			c.setConnectionState(StateDisconnected, lost.err)
+
+			if !c.reconnect() {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Attempt to establish a new connection after the previous one was lost.
+// Returns false if the client was closed or ran out of attempts.
+func (c *Client) reconnect() bool {
+	c.logger.Warn("reconnecting...")
+
+	for attempt := 1; ; attempt++ {
+		if c.isClosed() {
+			return false
+		}
+
+		err := c.run()
+		if err == nil {
+			c.logger.Info("reconnected after %d attempts", attempt)
+			return true
+		}
+
+		if c.backoff.MaxAttempts > 0 && attempt >= c.backoff.MaxAttempts {
+			c.logger.Error("giving up after %d reconnect attempts: %w", attempt, err)
+			c.setConnectionState(StateDisconnected, fmt.Errorf("%w: %w", ErrReconnectFailed, err))
+
+			return false
+		}
+
+		c.setConnectionState(StateDisconnected, err)
+
+		delay := c.backoff.delay(attempt)
+		c.logger.Info("reconnect failed, trying again in %s. attempt %d", delay, attempt)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.done:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// Return the delay after the given number of failed attempts.
+func (b Backoff) delay(attempt int) time.Duration {
+	delay := float64(b.Initial)
+	for i := 1; i < attempt && (b.Max <= 0 || delay < float64(b.Max)); i++ {
+		delay *= max(b.Multiplier, 1)
+	}
+
+	if b.Max > 0 {
+		delay = min(delay, float64(b.Max))
+	}
+
+	delay += delay * b.Jitter * (2*rand.Float64() - 1)
+
+	return time.Duration(max(delay, 0))
+}
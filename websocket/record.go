@@ -0,0 +1,371 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ryanjohnsontv/go-homeassistant/logging"
+)
+
+type (
+	// Frame is a single line of a recorded session.
+	Frame struct {
+		Time      time.Time       `json:"time"`
+		Direction Direction       `json:"direction"`
+		Message   json.RawMessage `json:"message,omitempty"`
+	}
+
+	// Direction of a recorded frame, relative to the client.
+	Direction string
+
+	recorder struct {
+		mu      sync.Mutex
+		encoder *json.Encoder
+	}
+
+	recordingConn struct {
+		Conn
+		recorder *recorder
+		logger   logging.Logger
+	}
+)
+
+const (
+	DirectionConnect Direction = "connect" // A new connection was dialed
+	DirectionIn      Direction = "in"      // Received from Home Assistant
+	DirectionOut     Direction = "out"     // Sent to Home Assistant
+)
+
+// WithRecorder writes every frame sent and received to w as JSON lines, one
+// Frame per line, including frames from reconnects. The access token is
+// redacted from the auth message. The recording can be fed back into a
+// client with NewReplay.
+func WithRecorder(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.recorder = &recorder{encoder: json.NewEncoder(w)}
+	}
+}
+
+func (r *recorder) record(direction Direction, msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.encoder.Encode(Frame{
+		Time:      time.Now().UTC(),
+		Direction: direction,
+		Message:   msg,
+	})
+}
+
+// Wrap a newly dialed connection so that its traffic is recorded.
+func (r *recorder) wrap(conn Conn, logger logging.Logger) Conn {
+	if err := r.record(DirectionConnect, nil); err != nil {
+		logger.Error("error recording connection: %w", err)
+	}
+
+	return &recordingConn{Conn: conn, recorder: r, logger: logger}
+}
+
+func (rc *recordingConn) ReadMessage() (int, []byte, error) {
+	msgType, msg, err := rc.Conn.ReadMessage()
+	if err == nil {
+		if err := rc.recorder.record(DirectionIn, msg); err != nil {
+			rc.logger.Error("error recording message: %w", err)
+		}
+	}
+
+	return msgType, msg, err
+}
+
+func (rc *recordingConn) WriteMessage(msgType int, data []byte) error {
+	if err := rc.recorder.record(DirectionOut, redactAuth(data)); err != nil {
+		rc.logger.Error("error recording message: %w", err)
+	}
+
+	return rc.Conn.WriteMessage(msgType, data)
+}
+
+// Replace the access token in auth messages. Other messages are returned as is.
+func redactAuth(msg []byte) []byte {
+	var auth map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &auth); err != nil {
+		return msg
+	}
+
+	if string(auth["type"]) != `"`+messageTypeAuth.String()+`"` {
+		return msg
+	}
+
+	auth["access_token"] = json.RawMessage(`"REDACTED"`)
+
+	redacted, err := json.Marshal(auth)
+	if err != nil {
+		return msg
+	}
+
+	return redacted
+}
+
+type (
+	// Replay plays back a session recorded with WithRecorder. Pass its Dial
+	// method to WithDialer; each dial replays the next recorded connection.
+	//
+	// A message from Home Assistant is delivered once the client has sent
+	// every message that preceded it in the recording, and message IDs are
+	// mapped onto the IDs the client actually uses, so a client issuing the
+	// same commands sees the same messages in the same order every time.
+	// Events are held back until Start is called so that listeners can be
+	// registered first; results are still delivered so commands work before
+	// then. Pings are answered immediately and recorded pings are skipped.
+	Replay struct {
+		mu          sync.Mutex
+		cond        *sync.Cond
+		connections []replaySession
+		dialed      int
+		started     bool
+	}
+
+	replaySession struct {
+		inbound  []replayFrame
+		outbound []json.RawMessage
+	}
+
+	replayFrame struct {
+		msg       json.RawMessage
+		event     bool
+		outBefore int // Number of messages the client sent before this one was received
+		delivered bool
+	}
+
+	replayConn struct {
+		replay  *Replay
+		session *replaySession
+		last    bool // The final recorded connection stays open once exhausted
+		next    int  // Index of the first inbound frame not yet delivered
+		written int
+		ids     map[int64]int64 // Recorded IDs to the IDs sent by the client
+		pongs   [][]byte
+		closed  bool
+	}
+)
+
+// NewReplay reads a recording written by WithRecorder.
+func NewReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{}
+	replay.cond = sync.NewCond(&replay.mu)
+
+	decoder := json.NewDecoder(r)
+
+	for {
+		var frame Frame
+
+		err := decoder.Decode(&frame)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading recording: %w", err)
+		}
+
+		if frame.Direction == DirectionConnect || len(replay.connections) == 0 {
+			replay.connections = append(replay.connections, replaySession{})
+		}
+
+		session := &replay.connections[len(replay.connections)-1]
+
+		var m incomingMsg
+		if len(frame.Message) > 0 {
+			if err := json.Unmarshal(frame.Message, &m); err != nil {
+				return nil, fmt.Errorf("error reading recorded message: %w", err)
+			}
+		}
+
+		if m.Type == messageTypePing || m.Type == messageTypePong {
+			continue
+		}
+
+		switch frame.Direction {
+		case DirectionIn:
+			session.inbound = append(session.inbound, replayFrame{
+				msg:       frame.Message,
+				event:     m.Type == messageTypeEvent,
+				outBefore: len(session.outbound),
+			})
+		case DirectionOut:
+			session.outbound = append(session.outbound, frame.Message)
+		case DirectionConnect:
+		default:
+			return nil, fmt.Errorf("unknown frame direction: %s", frame.Direction)
+		}
+	}
+
+	return replay, nil
+}
+
+// Dial replays the next recorded connection.
+func (r *Replay) Dial(ctx context.Context, _ string) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dialed >= len(r.connections) {
+		return nil, errors.New("no more recorded connections to replay")
+	}
+
+	conn := &replayConn{
+		replay:  r,
+		session: &r.connections[r.dialed],
+		last:    r.dialed == len(r.connections)-1,
+		ids:     make(map[int64]int64),
+	}
+
+	r.dialed++
+
+	return conn, nil
+}
+
+// Start delivers the recorded events.
+func (r *Replay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.started = true
+	r.cond.Broadcast()
+}
+
+func (rc *replayConn) ReadMessage() (int, []byte, error) {
+	r := rc.replay
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		if rc.closed {
+			return 0, nil, ErrConnectionClosed
+		}
+
+		if len(rc.pongs) > 0 {
+			pong := rc.pongs[0]
+			rc.pongs = rc.pongs[1:]
+
+			return websocket.TextMessage, pong, nil
+		}
+
+		inbound := rc.session.inbound
+		for rc.next < len(inbound) && inbound[rc.next].delivered {
+			rc.next++
+		}
+
+		if rc.next >= len(inbound) && !rc.last {
+			return 0, nil, io.EOF
+		}
+
+		for i := rc.next; i < len(inbound); i++ {
+			frame := &inbound[i]
+			if frame.delivered {
+				continue
+			}
+
+			if frame.outBefore > rc.written {
+				break
+			}
+
+			if frame.event && !r.started {
+				continue
+			}
+
+			frame.delivered = true
+
+			return websocket.TextMessage, rc.mapID(frame.msg), nil
+		}
+
+		r.cond.Wait()
+	}
+}
+
+func (rc *replayConn) WriteMessage(_ int, data []byte) error {
+	r := rc.replay
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rc.closed {
+		return ErrConnectionClosed
+	}
+
+	var m incomingMsg
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	if m.Type == messageTypePing {
+		rc.pongs = append(rc.pongs, []byte(fmt.Sprintf(`{"id":%d,"type":"pong"}`, m.ID)))
+	} else {
+		if rc.written < len(rc.session.outbound) {
+			var recorded incomingMsg
+			if err := json.Unmarshal(rc.session.outbound[rc.written], &recorded); err == nil && recorded.ID != 0 {
+				rc.ids[recorded.ID] = m.ID
+			}
+		}
+
+		rc.written++
+	}
+
+	r.cond.Broadcast()
+
+	return nil
+}
+
+func (rc *replayConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (rc *replayConn) Close() error {
+	r := rc.replay
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rc.closed = true
+	r.cond.Broadcast()
+
+	return nil
+}
+
+// Rewrite the ID of a recorded message to the ID the client used for the
+// matching command.
+func (rc *replayConn) mapID(msg json.RawMessage) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return msg
+	}
+
+	var recorded int64
+	if err := json.Unmarshal(m["id"], &recorded); err != nil {
+		return msg
+	}
+
+	id, exists := rc.ids[recorded]
+	if !exists {
+		return msg
+	}
+
+	m["id"] = json.RawMessage(fmt.Sprint(id))
+
+	mapped, err := json.Marshal(m)
+	if err != nil {
+		return msg
+	}
+
+	return mapped
+}
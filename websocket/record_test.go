@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/hatest"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/state"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	kitchen := mustParse(t, "light.kitchen")
+
+	// Register the same listeners on a client and return what they received.
+	listen := func(t *testing.T, client *Client, start func()) ([]state.Value, types.Event) {
+		t.Helper()
+
+		states := make(chan state.Value, 2)
+		_, err := client.AddEntityListener(kitchen, func(change *types.StateChange) {
+			states <- change.NewState.State
+		})
+		require.NoError(t, err)
+
+		events := make(chan types.Event, 1)
+		_, err = client.SubscribeToEvent("custom_event", func(e types.Event) {
+			events <- e
+		})
+		require.NoError(t, err)
+
+		start()
+
+		var received []state.Value
+
+		for len(received) < 2 {
+			select {
+			case s := <-states:
+				received = append(received, s)
+			case <-time.After(time.Second):
+				t.Fatal("entity listener was not called")
+			}
+		}
+
+		select {
+		case e := <-events:
+			return received, e
+		case <-time.After(time.Second):
+			t.Fatal("event callback was not called")
+		}
+
+		return nil, types.Event{}
+	}
+
+	server := hatest.NewServer(t, hatest.WithStates(types.Entity{EntityID: kitchen, State: "off"}))
+	recording := &syncBuffer{}
+	client := newTestClient(t, server, WithRecorder(recording))
+
+	recordedStates, recordedEvent := listen(t, client, func() {
+		server.SetState("light.kitchen", "on", nil)
+		server.SetState("light.kitchen", "off", nil)
+		server.FireEvent("custom_event", map[string]any{"foo": "bar"})
+	})
+	client.Close()
+
+	assert.NotContains(t, string(recording.Bytes()), server.Token)
+
+	replay, err := NewReplay(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+
+	replayed, err := NewClient("replay", "token", WithDialer(replay.Dial), WithCustomLogger(nopLogger{}))
+	require.NoError(t, err)
+	t.Cleanup(replayed.Close)
+
+	e, err := replayed.Entity(kitchen)
+	require.NoError(t, err)
+	assert.Equal(t, state.Value("off"), e.State)
+
+	replayedStates, replayedEvent := listen(t, replayed, replay.Start)
+
+	assert.ElementsMatch(t, recordedStates, replayedStates)
+	assert.Equal(t, recordedEvent, replayedEvent)
+}
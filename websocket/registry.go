@@ -0,0 +1,406 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/entity"
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Registry updates only send the fields being changed. Nil fields are left
+// unchanged and, unless noted otherwise, setting a pointer field to an empty
+// value clears it.
+type (
+	// EntityRegistryUpdate holds the changes to make to an entity registry
+	// entry. For example an empty AreaID removes the entity from its area and
+	// an empty HiddenBy unhides it.
+	EntityRegistryUpdate struct {
+		NewEntityID *entity.ID
+		Name        *string
+		Icon        *string
+		AreaID      *string
+		DeviceClass *string
+		DisabledBy  *types.DisabledBy
+		HiddenBy    *types.HiddenBy
+		Aliases     []string
+		Labels      []string
+		Categories  map[string]string
+	}
+
+	// DeviceRegistryUpdate holds the changes to make to a device.
+	DeviceRegistryUpdate struct {
+		NameByUser *string
+		AreaID     *string
+		DisabledBy *types.DisabledBy
+		Labels     []string
+	}
+
+	// AreaParams describes an area to create or the changes to make to one.
+	// Name is required when creating and left unchanged when empty on update.
+	// A zero HumidityEntityID or TemperatureEntityID clears it.
+	AreaParams struct {
+		Name                string
+		FloorID             *string
+		Icon                *string
+		Picture             *string
+		HumidityEntityID    *entity.ID
+		TemperatureEntityID *entity.ID
+		Aliases             []string
+		Labels              []string
+	}
+
+	// FloorParams describes a floor to create or the changes to make to one.
+	// Name is required when creating and left unchanged when empty on update.
+	// As 0 is a valid level, ClearLevel is used to remove the level instead.
+	FloorParams struct {
+		Name       string
+		Level      *int
+		ClearLevel bool // Sends the level as null, ignoring Level
+		Icon       *string
+		Aliases    []string
+	}
+
+	// LabelParams describes a label to create or the changes to make to one.
+	// Name is required when creating and left unchanged when empty on update.
+	LabelParams struct {
+		Name        string
+		Color       *string
+		Description *string
+		Icon        *string
+	}
+
+	// A command whose fields are only known at runtime.
+	fieldsRequest struct {
+		baseMessage
+		fields map[string]any
+	}
+)
+
+func newFieldsRequest(msgType messageType, fields map[string]any) *fieldsRequest {
+	return &fieldsRequest{
+		baseMessage: baseMessage{
+			Type: msgType,
+		},
+		fields: fields,
+	}
+}
+
+func (r *fieldsRequest) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(r.fields)+2)
+	for k, v := range r.fields {
+		fields[k] = v
+	}
+
+	fields["id"] = r.ID
+	fields["type"] = r.Type.String()
+
+	return json.Marshal(fields)
+}
+
+// Send a registry command and decode its result.
+func registryCommand[T any](
+	ctx context.Context,
+	c *Client,
+	msgType messageType,
+	fields map[string]any,
+) (T, error) {
+	var response T
+	if err := c.write(ctx, newFieldsRequest(msgType, fields), &response); err != nil {
+		c.logger.Error("%s failed: %w", msgType.String(), err)
+		return response, err
+	}
+
+	return response, nil
+}
+
+// ListEntityRegistry returns every entry in the entity registry.
+func (c *Client) ListEntityRegistry(ctx context.Context) ([]types.EntityRegistryEntry, error) {
+	return registryCommand[[]types.EntityRegistryEntry](ctx, c, messageTypeEntityRegistryList, nil)
+}
+
+// GetEntityRegistryEntry returns the registry entry of an entity.
+func (c *Client) GetEntityRegistryEntry(
+	ctx context.Context,
+	id entity.ID,
+) (types.EntityRegistryEntryExtended, error) {
+	return registryCommand[types.EntityRegistryEntryExtended](ctx, c, messageTypeEntityRegistryGet, map[string]any{
+		"entity_id": id,
+	})
+}
+
+// UpdateEntityRegistryEntry changes the registry entry of an entity, for
+// example to rename it, assign it to an area or hide it.
+func (c *Client) UpdateEntityRegistryEntry(
+	ctx context.Context,
+	id entity.ID,
+	update EntityRegistryUpdate,
+) (types.EntityRegistryUpdateResult, error) {
+	fields := update.fields()
+	fields["entity_id"] = id
+
+	return registryCommand[types.EntityRegistryUpdateResult](ctx, c, messageTypeEntityRegistryUpdate, fields)
+}
+
+// RemoveEntityRegistryEntry removes an entity from the registry. Only
+// entities whose integration no longer provides them can be removed.
+func (c *Client) RemoveEntityRegistryEntry(ctx context.Context, id entity.ID) error {
+	_, err := registryCommand[any](ctx, c, messageTypeEntityRegistryRemove, map[string]any{
+		"entity_id": id,
+	})
+
+	return err
+}
+
+// ListDevices returns every device in the device registry. Devices are
+// created by integrations so they can't be created or deleted directly.
+func (c *Client) ListDevices(ctx context.Context) ([]types.Device, error) {
+	return registryCommand[[]types.Device](ctx, c, messageTypeDeviceRegistryList, nil)
+}
+
+// UpdateDevice changes a device, for example to rename it or assign it to an area.
+func (c *Client) UpdateDevice(ctx context.Context, deviceID string, update DeviceRegistryUpdate) (types.Device, error) {
+	fields := map[string]any{"device_id": deviceID}
+
+	setNullable(fields, "name_by_user", update.NameByUser)
+	setNullable(fields, "area_id", update.AreaID)
+	setNullable(fields, "disabled_by", update.DisabledBy)
+
+	if update.Labels != nil {
+		fields["labels"] = update.Labels
+	}
+
+	return registryCommand[types.Device](ctx, c, messageTypeDeviceRegistryUpdate, fields)
+}
+
+// RemoveDeviceConfigEntry removes a config entry from a device. The device is
+// deleted once it has no config entries left.
+func (c *Client) RemoveDeviceConfigEntry(ctx context.Context, deviceID, configEntryID string) error {
+	_, err := registryCommand[any](ctx, c, messageTypeDeviceRegistryRemoveConfigEntry, map[string]any{
+		"device_id":       deviceID,
+		"config_entry_id": configEntryID,
+	})
+
+	return err
+}
+
+// ListAreas returns every area in the area registry.
+func (c *Client) ListAreas(ctx context.Context) ([]types.Area, error) {
+	return registryCommand[[]types.Area](ctx, c, messageTypeAreaRegistryList, nil)
+}
+
+// CreateArea adds an area to the registry.
+func (c *Client) CreateArea(ctx context.Context, params AreaParams) (types.Area, error) {
+	return registryCommand[types.Area](ctx, c, messageTypeAreaRegistryCreate, params.fields())
+}
+
+// UpdateArea changes an area.
+func (c *Client) UpdateArea(ctx context.Context, areaID string, params AreaParams) (types.Area, error) {
+	fields := params.fields()
+	fields["area_id"] = areaID
+
+	return registryCommand[types.Area](ctx, c, messageTypeAreaRegistryUpdate, fields)
+}
+
+// DeleteArea removes an area from the registry.
+func (c *Client) DeleteArea(ctx context.Context, areaID string) error {
+	_, err := registryCommand[any](ctx, c, messageTypeAreaRegistryDelete, map[string]any{"area_id": areaID})
+	return err
+}
+
+// ListFloors returns every floor in the floor registry.
+func (c *Client) ListFloors(ctx context.Context) ([]types.Floor, error) {
+	return registryCommand[[]types.Floor](ctx, c, messageTypeFloorRegistryList, nil)
+}
+
+// CreateFloor adds a floor to the registry.
+func (c *Client) CreateFloor(ctx context.Context, params FloorParams) (types.Floor, error) {
+	return registryCommand[types.Floor](ctx, c, messageTypeFloorRegistryCreate, params.fields())
+}
+
+// UpdateFloor changes a floor.
+func (c *Client) UpdateFloor(ctx context.Context, floorID string, params FloorParams) (types.Floor, error) {
+	fields := params.fields()
+	fields["floor_id"] = floorID
+
+	return registryCommand[types.Floor](ctx, c, messageTypeFloorRegistryUpdate, fields)
+}
+
+// DeleteFloor removes a floor from the registry.
+func (c *Client) DeleteFloor(ctx context.Context, floorID string) error {
+	_, err := registryCommand[any](ctx, c, messageTypeFloorRegistryDelete, map[string]any{"floor_id": floorID})
+	return err
+}
+
+// ListLabels returns every label in the label registry.
+func (c *Client) ListLabels(ctx context.Context) ([]types.Label, error) {
+	return registryCommand[[]types.Label](ctx, c, messageTypeLabelRegistryList, nil)
+}
+
+// CreateLabel adds a label to the registry.
+func (c *Client) CreateLabel(ctx context.Context, params LabelParams) (types.Label, error) {
+	return registryCommand[types.Label](ctx, c, messageTypeLabelRegistryCreate, params.fields())
+}
+
+// UpdateLabel changes a label.
+func (c *Client) UpdateLabel(ctx context.Context, labelID string, params LabelParams) (types.Label, error) {
+	fields := params.fields()
+	fields["label_id"] = labelID
+
+	return registryCommand[types.Label](ctx, c, messageTypeLabelRegistryUpdate, fields)
+}
+
+// DeleteLabel removes a label from the registry.
+func (c *Client) DeleteLabel(ctx context.Context, labelID string) error {
+	_, err := registryCommand[any](ctx, c, messageTypeLabelRegistryDelete, map[string]any{"label_id": labelID})
+	return err
+}
+
+// ResolveTarget expands a target into the entities a service call would act
+// on, using the current registries. See types.Registries.Resolve.
+func (c *Client) ResolveTarget(ctx context.Context, target types.ServiceTarget) (entity.IDList, error) {
+	registries, err := c.registries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return registries.Resolve(target), nil
+}
+
+// Fetch the registries needed to resolve targets, or use the cache if enabled.
+func (c *Client) registries(ctx context.Context) (types.Registries, error) {
+	if c.registryCache != nil {
+		return c.Registries()
+	}
+
+	var (
+		registries types.Registries
+		err        error
+	)
+
+	if registries.Entities, err = c.ListEntityRegistry(ctx); err != nil {
+		return registries, err
+	}
+
+	if registries.Devices, err = c.ListDevices(ctx); err != nil {
+		return registries, err
+	}
+
+	if registries.Areas, err = c.ListAreas(ctx); err != nil {
+		return registries, err
+	}
+
+	return registries, nil
+}
+
+func (u EntityRegistryUpdate) fields() map[string]any {
+	fields := make(map[string]any)
+
+	if u.NewEntityID != nil {
+		fields["new_entity_id"] = u.NewEntityID
+	}
+
+	setNullable(fields, "name", u.Name)
+	setNullable(fields, "icon", u.Icon)
+	setNullable(fields, "area_id", u.AreaID)
+	setNullable(fields, "device_class", u.DeviceClass)
+	setNullable(fields, "disabled_by", u.DisabledBy)
+	setNullable(fields, "hidden_by", u.HiddenBy)
+
+	if u.Aliases != nil {
+		fields["aliases"] = u.Aliases
+	}
+
+	if u.Labels != nil {
+		fields["labels"] = u.Labels
+	}
+
+	if u.Categories != nil {
+		fields["categories"] = u.Categories
+	}
+
+	return fields
+}
+
+func (p AreaParams) fields() map[string]any {
+	fields := make(map[string]any)
+
+	if p.Name != "" {
+		fields["name"] = p.Name
+	}
+
+	setNullable(fields, "floor_id", p.FloorID)
+	setNullable(fields, "icon", p.Icon)
+	setNullable(fields, "picture", p.Picture)
+	setNullableEntity(fields, "humidity_entity_id", p.HumidityEntityID)
+	setNullableEntity(fields, "temperature_entity_id", p.TemperatureEntityID)
+
+	if p.Aliases != nil {
+		fields["aliases"] = p.Aliases
+	}
+
+	if p.Labels != nil {
+		fields["labels"] = p.Labels
+	}
+
+	return fields
+}
+
+func (p FloorParams) fields() map[string]any {
+	fields := make(map[string]any)
+
+	if p.Name != "" {
+		fields["name"] = p.Name
+	}
+
+	switch {
+	case p.ClearLevel:
+		fields["level"] = nil
+	case p.Level != nil:
+		fields["level"] = *p.Level
+	}
+
+	setNullable(fields, "icon", p.Icon)
+
+	if p.Aliases != nil {
+		fields["aliases"] = p.Aliases
+	}
+
+	return fields
+}
+
+func (p LabelParams) fields() map[string]any {
+	fields := make(map[string]any)
+
+	if p.Name != "" {
+		fields["name"] = p.Name
+	}
+
+	setNullable(fields, "color", p.Color)
+	setNullable(fields, "description", p.Description)
+	setNullable(fields, "icon", p.Icon)
+
+	return fields
+}
+
+// Add a field that is being changed, sending an empty value as null.
+func setNullable[T ~string](fields map[string]any, key string, value *T) {
+	switch {
+	case value == nil:
+	case *value == "":
+		fields[key] = nil
+	default:
+		fields[key] = *value
+	}
+}
+
+// Add an entity ID that is being changed, sending the zero ID as null.
+func setNullableEntity(fields map[string]any, key string, value *entity.ID) {
+	switch {
+	case value == nil:
+	case *value == entity.ID{}:
+		fields[key] = nil
+	default:
+		fields[key] = *value
+	}
+}
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// ErrRegistryCacheDisabled is returned by Registries when the client was
+// created without WithRegistryCache.
+var ErrRegistryCacheDisabled = errors.New("registry cache is not enabled")
+
+type (
+	registryCache struct {
+		loaders    map[string]*registryLoader // By registry updated event type
+		registries types.Registries           // Guarded by Client.mu
+	}
+
+	// Serializes reloads of one registry and coalesces reloads requested
+	// while another is waiting to start.
+	registryLoader struct {
+		mu     sync.Mutex
+		queued atomic.Bool
+		load   func(ctx context.Context, c *Client) (func(*types.Registries), error)
+	}
+)
+
+// WithRegistryCache keeps the entity, device, area, floor and label registries
+// in memory. They are loaded when connecting and reloaded whenever Home
+// Assistant reports a change, so Registries can be queried without round
+// trips. ResolveTarget uses the cache when it is enabled.
+func WithRegistryCache() ClientOption {
+	return func(c *Client) {
+		c.registryCache = &registryCache{
+			loaders: map[string]*registryLoader{
+				"entity_registry_updated": {load: func(ctx context.Context, c *Client) (func(*types.Registries), error) {
+					entries, err := c.ListEntityRegistry(ctx)
+					return func(r *types.Registries) { r.Entities = entries }, err
+				}},
+				"device_registry_updated": {load: func(ctx context.Context, c *Client) (func(*types.Registries), error) {
+					devices, err := c.ListDevices(ctx)
+					return func(r *types.Registries) { r.Devices = devices }, err
+				}},
+				"area_registry_updated": {load: func(ctx context.Context, c *Client) (func(*types.Registries), error) {
+					areas, err := c.ListAreas(ctx)
+					return func(r *types.Registries) { r.Areas = areas }, err
+				}},
+				"floor_registry_updated": {load: func(ctx context.Context, c *Client) (func(*types.Registries), error) {
+					floors, err := c.ListFloors(ctx)
+					return func(r *types.Registries) { r.Floors = floors }, err
+				}},
+				"label_registry_updated": {load: func(ctx context.Context, c *Client) (func(*types.Registries), error) {
+					labels, err := c.ListLabels(ctx)
+					return func(r *types.Registries) { r.Labels = labels }, err
+				}},
+			},
+		}
+	}
+}
+
+// Registries returns the cached registries. The returned slices are replaced,
+// never modified, when the cache is updated so they are safe to keep.
+func (c *Client) Registries() (types.Registries, error) {
+	if c.registryCache == nil {
+		return types.Registries{}, ErrRegistryCacheDisabled
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.registryCache.registries, nil
+}
+
+// Subscribe to registry updates, then load every cached registry. Subscriptions
+// are replayed on reconnect so this only runs once.
+func (c *Client) startRegistryCache(ctx context.Context) error {
+	for eventType, loader := range c.registryCache.loaders {
+		request := subscribeToEventRequest{
+			baseMessage: baseMessage{
+				Type: messageTypeSubscribeEvent,
+			},
+			EventType: eventType,
+		}
+
+		handler := eventHandler{
+			EventType: eventType,
+			Callback: func(types.Event) {
+				c.reloadRegistry(loader)
+			},
+		}
+
+		if err := c.subscribeToEvent(ctx, &request, handler); err != nil {
+			return err
+		}
+	}
+
+	// Load after subscribing so that changes made in between aren't missed
+	return c.loadRegistries(ctx)
+}
+
+// Load every cached registry, used on connect and after reconnecting when
+// updates may have been missed.
+func (c *Client) loadRegistries(ctx context.Context) error {
+	for _, loader := range c.registryCache.loaders {
+		if err := c.loadRegistry(ctx, loader); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) loadRegistry(ctx context.Context, loader *registryLoader) error {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	loader.queued.Store(false)
+
+	apply, err := loader.load(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	apply(&c.registryCache.registries)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Reload a registry after an update event. Loads are serialized and each one
+// starts after the event that requested it, so the last load to finish always
+// holds the newest registry. Events arriving while a load is still waiting to
+// start are covered by that load.
+func (c *Client) reloadRegistry(loader *registryLoader) {
+	if !loader.queued.CompareAndSwap(false, true) {
+		return
+	}
+
+	if err := c.loadRegistry(context.Background(), loader); err != nil {
+		c.logger.Error("failed to reload registry: %w", err)
+	}
+}
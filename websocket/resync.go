@@ -0,0 +1,158 @@
+package websocket
+
+import (
+	"context"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+// Replay every active event and trigger subscription on the current connection.
+// Handlers are moved from the ID of the previous subscription to the ID of the
+// replayed one. A subscription that fails to replay stays registered under its
+// old ID and is retried on the next reconnect.
+func (c *Client) resubscribe(ctx context.Context) {
+	c.mu.RLock()
+	events := make(map[int64]eventHandler, len(c.eventHandler))
+	for id, handler := range c.eventHandler {
+		events[id] = handler
+	}
+
+	triggers := make(map[int64]triggerHandler, len(c.triggerHandler))
+	for id, handler := range c.triggerHandler {
+		triggers[id] = handler
+	}
+	c.mu.RUnlock()
+
+	for oldID, handler := range events {
+		if !c.hasEventHandler(oldID) {
+			continue
+		}
+
+		if err := c.write(ctx, handler.request, nil, registerHandler(func(id int64) {
+			c.eventHandler[id] = handler
+		}, func(id int64) {
+			delete(c.eventHandler, id)
+		})); err != nil {
+			c.logger.Error("failed to resubscribe to %s: %w", handler.EventType, err)
+			continue
+		}
+
+		c.mu.Lock()
+		delete(c.eventHandler, oldID)
+		c.mu.Unlock()
+
+		c.logger.Debug("resubscribed to %s: %d -> %d", handler.EventType, oldID, handler.request.GetID())
+	}
+
+	for oldID, handler := range triggers {
+		if !c.hasTriggerHandler(oldID) {
+			continue
+		}
+
+		if err := c.write(ctx, handler.request, nil, registerHandler(func(id int64) {
+			c.triggerHandler[id] = handler
+		}, func(id int64) {
+			delete(c.triggerHandler, id)
+		})); err != nil {
+			c.logger.Error("failed to resubscribe to trigger: %w", err)
+			continue
+		}
+
+		c.mu.Lock()
+		delete(c.triggerHandler, oldID)
+		c.mu.Unlock()
+
+		c.logger.Debug("resubscribed to trigger: %d -> %d", oldID, handler.request.GetID())
+	}
+}
+
+// Report whether an event handler is still registered. Handlers unsubscribed
+// while a replay is in progress are skipped.
+func (c *Client) hasEventHandler(id int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.eventHandler[id]
+
+	return exists
+}
+
+// Report whether a trigger handler is still registered.
+func (c *Client) hasTriggerHandler(id int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.triggerHandler[id]
+
+	return exists
+}
+
+// Fetch all states and replace EntitiesMap, firing synthetic state changes for
+// every entity that was added, changed or removed while disconnected.
+func (c *Client) resyncStates(ctx context.Context) error {
+	request := baseMessage{
+		Type: messageTypeGetStates,
+	}
+
+	var response types.Entities
+	if err := c.write(ctx, &request, &response); err != nil {
+		c.logger.Error("failed to resync states: %w", err)
+		return err
+	}
+
+	c.replaceStates(response.SortStates())
+
+	c.logger.Info("states resynced")
+
+	return nil
+}
+
+// Replace EntitiesMap, firing synthetic state changes for every entity that
+// was added, changed or removed.
+func (c *Client) replaceStates(current types.EntitiesMap) {
+	c.mu.Lock()
+	previous := c.EntitiesMap
+	c.EntitiesMap = current
+	c.mu.Unlock()
+
+	for _, change := range stateChanges(previous, current) {
+		c.dispatchStateChange(change)
+	}
+}
+
+// The state changes between two sets of states. Entities are considered
+// changed if they were updated since the previous state.
+func stateChanges(previous, current types.EntitiesMap) []*types.StateChange {
+	var changes []*types.StateChange
+
+	for entityID, newState := range current {
+		oldState, existed := previous[entityID]
+		if existed && oldState.LastUpdated.Equal(newState.LastUpdated) {
+			continue
+		}
+
+		change := &types.StateChange{
+			EntityID: entityID,
+			NewState: &newState,
+		}
+
+		if existed {
+			change.OldState = &oldState
+		}
+
+		changes = append(changes, change)
+	}
+
+	for entityID, oldState := range previous {
+		if _, exists := current[entityID]; exists {
+			continue
+		}
+
+		changes = append(changes, &types.StateChange{
+			EntityID: entityID,
+			OldState: &oldState,
+		})
+	}
+
+	return changes
+}
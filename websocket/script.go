@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	validateConfigRequest struct {
+		baseMessage
+		Trigger   any `json:"trigger,omitempty"`
+		Condition any `json:"condition,omitempty"`
+		Action    any `json:"action,omitempty"`
+	}
+
+	executeScriptRequest struct {
+		baseMessage
+		Sequence  any            `json:"sequence"`
+		Variables map[string]any `json:"variables,omitempty"`
+	}
+)
+
+// ValidateConfig checks automation triggers, conditions and actions without
+// running them. Pass nil for sections that shouldn't be validated. An invalid
+// config is not an error, check the returned ConfigValidation.
+func (c *Client) ValidateConfig(ctx context.Context, trigger, condition, action any) (types.ConfigValidation, error) {
+	request := validateConfigRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeValidateConfig,
+		},
+		Trigger:   trigger,
+		Condition: condition,
+		Action:    action,
+	}
+
+	var validation types.ConfigValidation
+	if err := c.write(ctx, &request, &validation); err != nil {
+		c.logger.Error("failed to validate config: %w", err)
+		return validation, err
+	}
+
+	return validation, nil
+}
+
+// ExecuteScript runs a sequence of actions, as used in scripts and automations,
+// with the given variables. If the sequence stops with a response_variable the
+// response is decoded into response, which may be nil.
+func (c *Client) ExecuteScript(
+	ctx context.Context,
+	sequence any,
+	variables map[string]any,
+	response any,
+) (types.Context, error) {
+	request := executeScriptRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeExecuteScript,
+		},
+		Sequence:  sequence,
+		Variables: variables,
+	}
+
+	var result struct {
+		contextResult
+		Response json.RawMessage `json:"response"`
+	}
+
+	if err := c.write(ctx, &request, &result); err != nil {
+		c.logger.Error("failed to execute script: %w", err)
+		return result.Context, err
+	}
+
+	if response != nil && result.Response != nil {
+		if err := json.Unmarshal(result.Response, response); err != nil {
+			return result.Context, fmt.Errorf("failed to unmarshal script response: %w", err)
+		}
+	}
+
+	return result.Context, nil
+}
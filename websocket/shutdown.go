@@ -0,0 +1,181 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracks the goroutines running callbacks so that Shutdown can wait for them.
+// Once stopped no new ones are started.
+type callbackGroup struct {
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// Run f in a new goroutine. Returns false without running f once stopped.
+func (g *callbackGroup) run(f func()) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.stopped {
+		return false
+	}
+
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+		f()
+	}()
+
+	return true
+}
+
+func (g *callbackGroup) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stopped = true
+}
+
+// Run f in a new goroutine that Shutdown waits for. Only used before Shutdown
+// starts waiting or from goroutines it is already waiting for.
+func (c *Client) goRoutine(f func()) {
+	c.routines.Add(1)
+
+	go func() {
+		defer c.routines.Done()
+		f()
+	}()
+}
+
+// Return a context that is cancelled once the client is closed.
+func (c *Client) untilClosed() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Shutdown closes the client gracefully. New commands fail with
+// ErrClientClosed right away, while commands already sent get until ctx is
+// done to finish. Server side subscriptions are then cancelled and the
+// connection closed. Events arriving in the meantime are dropped, but
+// callbacks that are already running are waited for.
+//
+// Once Shutdown returns nil every goroutine started by the client has exited.
+// If ctx is done first the client is still closed, and ctx.Err() is returned
+// while commands or callbacks may still be running.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock()
+
+	c.logger.Info("shutting down")
+
+	// Reconnect attempts in progress are abandoned, wait for the supervisor
+	// so that the connection can't be replaced after it is closed below
+	err := wait(ctx, &c.supervising)
+	if err == nil {
+		err = wait(ctx, &c.inflight)
+	}
+
+	if err == nil {
+		c.unsubscribeAll(ctx)
+	}
+
+	if conn := c.currentConn(); conn != nil {
+		conn.close()
+	}
+
+	if err == nil {
+		err = wait(ctx, &c.routines)
+	}
+
+	c.setConnectionState(StateDisconnected, nil)
+	c.callbacks.stop()
+
+	if err == nil {
+		err = wait(ctx, &c.callbacks.wg)
+	}
+
+	return err
+}
+
+// Close shuts the client down, waiting up to the client timeout for commands
+// and callbacks. See Shutdown.
+func (c *Client) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := c.Shutdown(ctx); err != nil {
+		c.logger.Warn("client did not shut down cleanly: %s", err.Error())
+	}
+}
+
+// Cancel every server side subscription. Failures are only logged since the
+// connection is closed right after.
+func (c *Client) unsubscribeAll(ctx context.Context) {
+	c.mu.Lock()
+	ids := make([]int64, 0, len(c.eventHandler)+len(c.triggerHandler))
+
+	for id := range c.eventHandler {
+		ids = append(ids, id)
+		delete(c.eventHandler, id)
+	}
+
+	for id := range c.triggerHandler {
+		ids = append(ids, id)
+		delete(c.triggerHandler, id)
+	}
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		unsubscribe := unsubscribeEventsRequest{
+			baseMessage: baseMessage{
+				Type: messageTypeUnsubscribeEvents,
+			},
+			Subscription: id,
+		}
+
+		if err := c.write(ctx, &unsubscribe, nil, whileClosing()); err != nil {
+			c.logger.Warn("failed to unsubscribe from subscription %d: %s", id, err.Error())
+		}
+	}
+}
+
+// Wait for wg or until ctx is done. The goroutine waiting on wg exits once
+// the group is done, even if ctx was done first.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
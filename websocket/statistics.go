@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	// StatisticsOptions change which statistics are returned.
+	StatisticsOptions struct {
+		EndTime time.Time             // Defaults to now
+		Types   []types.StatisticType // Defaults to every type
+		// Units to convert to by unit class, such as "energy": "kWh" or
+		// "temperature": "°C". Defaults to the display unit of each statistic.
+		Units map[string]string
+	}
+
+	// StatisticImportMetadata describes statistics to import. Statistics from
+	// the recorder source belong to an entity and use its entity ID, external
+	// statistics use "source:name" IDs, such as "energy_provider:usage".
+	// UnitClass and MeanType are left out when nil, for versions of Home
+	// Assistant that don't accept them. Newer versions check that the unit
+	// belongs to UnitClass and use MeanType in place of HasMean.
+	StatisticImportMetadata struct {
+		StatisticID       string                   `json:"statistic_id"`
+		Source            string                   `json:"source"`
+		Name              *string                  `json:"name"`
+		UnitOfMeasurement *string                  `json:"unit_of_measurement"`
+		UnitClass         *string                  `json:"unit_class,omitempty"`
+		HasMean           bool                     `json:"has_mean"`
+		MeanType          *types.StatisticMeanType `json:"mean_type,omitempty"`
+		HasSum            bool                     `json:"has_sum"`
+	}
+
+	// StatisticImport is the value of a statistic for one hour. Start must be
+	// at the start of an hour.
+	StatisticImport struct {
+		Start     time.Time
+		Mean      *float64
+		Min       *float64
+		Max       *float64
+		State     *float64
+		Sum       *float64
+		LastReset *time.Time
+	}
+
+	statisticsDuringPeriodRequest struct {
+		baseMessage
+		StartTime    string                `json:"start_time"`
+		EndTime      string                `json:"end_time,omitempty"`
+		StatisticIDs []string              `json:"statistic_ids"`
+		Period       types.StatisticPeriod `json:"period"`
+		Types        []types.StatisticType `json:"types,omitempty"`
+		Units        map[string]string     `json:"units,omitempty"`
+	}
+
+	listStatisticIDsRequest struct {
+		baseMessage
+		StatisticType types.StatisticType `json:"statistic_type,omitempty"`
+	}
+
+	getStatisticsMetadataRequest struct {
+		baseMessage
+		StatisticIDs []string `json:"statistic_ids,omitempty"`
+	}
+
+	importStatisticsRequest struct {
+		baseMessage
+		Metadata StatisticImportMetadata `json:"metadata"`
+		Stats    []StatisticImport       `json:"stats"`
+	}
+)
+
+// StatisticsDuringPeriod returns statistics from start grouped by period, by
+// statistic ID. Statistics without data in the range are left out.
+func (c *Client) StatisticsDuringPeriod(
+	ctx context.Context,
+	start time.Time,
+	statisticIDs []string,
+	period types.StatisticPeriod,
+	opts StatisticsOptions,
+) (map[string][]types.Statistic, error) {
+	request := statisticsDuringPeriodRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeStatisticsDuringPeriod,
+		},
+		StartTime:    formatTime(start),
+		EndTime:      formatTime(opts.EndTime),
+		StatisticIDs: statisticIDs,
+		Period:       period,
+		Types:        opts.Types,
+		Units:        opts.Units,
+	}
+
+	var statistics map[string][]types.Statistic
+	if err := c.write(ctx, &request, &statistics); err != nil {
+		c.logger.Error("failed to get statistics: %w", err)
+		return nil, err
+	}
+
+	return statistics, nil
+}
+
+// ListStatisticIDs returns every statistic, or only those with a mean or sum if
+// statisticType is types.StatisticTypeMean or types.StatisticTypeSum.
+func (c *Client) ListStatisticIDs(ctx context.Context, statisticType types.StatisticType) ([]types.StatisticMetadata, error) {
+	request := listStatisticIDsRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeListStatisticIDs,
+		},
+		StatisticType: statisticType,
+	}
+
+	var metadata []types.StatisticMetadata
+	if err := c.write(ctx, &request, &metadata); err != nil {
+		c.logger.Error("failed to list statistic IDs: %w", err)
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// GetStatisticsMetadata returns the metadata of statistics, or of every
+// statistic if no IDs are given.
+func (c *Client) GetStatisticsMetadata(ctx context.Context, statisticIDs ...string) ([]types.StatisticMetadata, error) {
+	request := getStatisticsMetadataRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeGetStatisticsMetadata,
+		},
+		StatisticIDs: statisticIDs,
+	}
+
+	var metadata []types.StatisticMetadata
+	if err := c.write(ctx, &request, &metadata); err != nil {
+		c.logger.Error("failed to get statistics metadata: %w", err)
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// ImportStatistics adds or replaces hourly statistics. Home Assistant compiles
+// the longer periods from them.
+func (c *Client) ImportStatistics(ctx context.Context, metadata StatisticImportMetadata, stats []StatisticImport) error {
+	request := importStatisticsRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeImportStatistics,
+		},
+		Metadata: metadata,
+		Stats:    stats,
+	}
+
+	if err := c.write(ctx, &request, nil); err != nil {
+		c.logger.Error("failed to import statistics: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s StatisticImport) MarshalJSON() ([]byte, error) {
+	stat := map[string]any{"start": formatTime(s.Start)}
+
+	for key, value := range map[string]*float64{
+		"mean":  s.Mean,
+		"min":   s.Min,
+		"max":   s.Max,
+		"state": s.State,
+		"sum":   s.Sum,
+	} {
+		if value != nil {
+			stat[key] = *value
+		}
+	}
+
+	if s.LastReset != nil {
+		stat["last_reset"] = formatTime(*s.LastReset)
+	}
+
+	return json.Marshal(stat)
+}
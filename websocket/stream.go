@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Send a command that streams events until it is unsubscribed. Events are
+// passed to raw undecoded and in order, as each one may build on the last.
+func (c *Client) subscribeStream(
+	ctx context.Context,
+	request cmdMessage,
+	name string,
+	raw func(json.RawMessage),
+) (*Subscription, error) {
+	queue := &serialQueue{group: &c.callbacks}
+	handler := eventHandler{
+		EventType: name,
+		request:   request,
+		raw: func(event json.RawMessage) {
+			queue.push(func() { raw(event) })
+		},
+	}
+
+	if err := c.write(ctx, request, nil, registerHandler(func(id int64) {
+		c.eventHandler[id] = handler
+	}, func(id int64) {
+		delete(c.eventHandler, id)
+	})); err != nil {
+		c.logger.Error("failed to subscribe to %s: %w", name, err)
+		return nil, err
+	}
+
+	c.logger.Info("subscribed to %s", name)
+
+	return newSubscription(func(ctx context.Context) error {
+		return c.unsubscribeEvents(ctx, request, func(id int64) {
+			delete(c.eventHandler, id)
+		})
+	}), nil
+}
+
+// Runs functions one at a time in the order they were pushed, without
+// blocking the caller. The goroutine running them exits once none are left.
+type serialQueue struct {
+	group   *callbackGroup
+	mu      sync.Mutex
+	pending []func()
+	running bool
+}
+
+func (q *serialQueue) push(f func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending = append(q.pending, f)
+
+	if !q.running {
+		q.running = q.group.run(q.run)
+	}
+}
+
+func (q *serialQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+
+			return
+		}
+
+		f := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		f()
+	}
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscription is returned when subscribing to events, triggers or entity
+// state changes and is used to detach the callback again.
+type Subscription struct {
+	once        sync.Once
+	err         error
+	unsubscribe func(ctx context.Context) error
+}
+
+func newSubscription(unsubscribe func(ctx context.Context) error) *Subscription {
+	return &Subscription{unsubscribe: unsubscribe}
+}
+
+// Unsubscribe stops the callback from being called. Server side subscriptions
+// are cancelled with an unsubscribe_events command. Calling Unsubscribe more
+// than once returns the result of the first call.
+func (s *Subscription) Unsubscribe() error {
+	return s.UnsubscribeContext(context.Background())
+}
+
+// UnsubscribeContext is like Unsubscribe but uses the provided context.
+func (s *Subscription) UnsubscribeContext(ctx context.Context) error {
+	s.once.Do(func() {
+		s.err = s.unsubscribe(ctx)
+	})
+
+	return s.err
+}
+
+type unsubscribeEventsRequest struct {
+	baseMessage
+	Subscription int64 `json:"subscription"`
+}
+
+// Cancel a server side subscription. The handler is removed locally first so
+// it is neither called nor replayed after a reconnect, even if the command fails.
+func (c *Client) unsubscribeEvents(ctx context.Context, request cmdMessage, remove func(id int64)) error {
+	c.mu.Lock()
+	id := request.GetID()
+	remove(id)
+	c.mu.Unlock()
+
+	unsubscribe := unsubscribeEventsRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeUnsubscribeEvents,
+		},
+		Subscription: id,
+	}
+
+	if err := c.write(ctx, &unsubscribe, nil); err != nil {
+		c.logger.Error("failed to unsubscribe from subscription %d: %w", id, err)
+		return err
+	}
+
+	c.logger.Info("unsubscribed from subscription %d", id)
+
+	return nil
+}
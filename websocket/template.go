@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ryanjohnsontv/go-homeassistant/shared/types"
+)
+
+type (
+	renderTemplateRequest struct {
+		baseMessage
+		Template     string         `json:"template"`
+		Variables    map[string]any `json:"variables,omitempty"`
+		Timeout      float64        `json:"timeout,omitempty"` // Seconds
+		Strict       bool           `json:"strict,omitempty"`
+		ReportErrors bool           `json:"report_errors,omitempty"`
+	}
+
+	TemplateOption func(*renderTemplateRequest)
+)
+
+// TemplateStrict fails rendering when the template uses undefined variables.
+func TemplateStrict() TemplateOption {
+	return func(r *renderTemplateRequest) {
+		r.Strict = true
+	}
+}
+
+// TemplateReportErrors sends errors of later renders to the callback with
+// TemplateRender.Error set. Errors in the first render still fail the subscription.
+func TemplateReportErrors() TemplateOption {
+	return func(r *renderTemplateRequest) {
+		r.ReportErrors = true
+	}
+}
+
+// TemplateTimeout fails the subscription if the first render takes longer than timeout.
+func TemplateTimeout(timeout time.Duration) TemplateOption {
+	return func(r *renderTemplateRequest) {
+		r.Timeout = timeout.Seconds()
+	}
+}
+
+// SubscribeTemplate renders a template and calls f with the result, then again
+// every time Home Assistant renders it because something it references changed.
+func (c *Client) SubscribeTemplate(
+	ctx context.Context,
+	template string,
+	variables map[string]any,
+	f func(types.TemplateRender),
+	opts ...TemplateOption,
+) (*Subscription, error) {
+	request := renderTemplateRequest{
+		baseMessage: baseMessage{
+			Type: messageTypeRenderTemplate,
+		},
+		Template:  template,
+		Variables: variables,
+	}
+
+	for _, opt := range opts {
+		opt(&request)
+	}
+
+	return c.subscribeStream(ctx, &request, "render_template", func(event json.RawMessage) {
+		var render types.TemplateRender
+		if err := json.Unmarshal(event, &render); err != nil {
+			c.logger.Error("error unmarshalling template render: %w", err)
+			return
+		}
+
+		f(render)
+	})
+}
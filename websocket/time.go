@@ -21,27 +21,47 @@ type (
 )
 
 // TriggerDateTime initiates a ticker that triggers callbacks at specified times.
+// It returns once the client is closed.
 func (c *Client) TriggerDateTime() {
-	if len(c.dateTimeEntityListeners) == 0 {
+	c.mu.RLock()
+	listeners := len(c.dateTimeEntityListeners)
+	c.mu.RUnlock()
+
+	if listeners == 0 {
 		return
 	}
 	// Wait until the start of the next minute to begin the ticker.
 	now := time.Now()
-	untilNextMinute := time.Until(now.Truncate(time.Minute).Add(time.Minute))
-	time.Sleep(untilNextMinute)
+	untilNextMinute := time.NewTimer(time.Until(now.Truncate(time.Minute).Add(time.Minute)))
+	defer untilNextMinute.Stop()
+
+	select {
+	case <-untilNextMinute.C:
+	case <-c.done:
+		return
+	}
 
 	// Set up a ticker that ticks every minute.
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
-	for t := range ticker.C {
-		c.triggerCallbacks(t)
+	for {
+		select {
+		case t := <-ticker.C:
+			c.triggerCallbacks(t)
+		case <-c.done:
+			return
+		}
 	}
 }
 
 // triggerCallbacks triggers the appropriate callbacks for a given time.
 func (c *Client) triggerCallbacks(t time.Time) {
 	currentHour, currentMinute, _ := t.Clock()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	for entityTime, dateTimeMap := range c.dateTimeEntityListeners {
 		entityHour, entityMinute, _ := entityTime.Clock()
 
@@ -59,7 +79,7 @@ func (c *Client) executeCallbacks(dateTimeMap map[entity.ID][]dateTimeEntityTrig
 			c.logger.Debug("triggering datetime entity callback function for %s", entityID)
 
 			if eventTrigger.timeType == timeOnly {
-				go eventTrigger.callback()
+				c.callbacks.run(eventTrigger.callback)
 			}
 		}
 	}